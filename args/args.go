@@ -1,9 +1,13 @@
 package args
 
 import (
+	"encoding/csv"
 	"github.com/spf13/cobra"
+	"io"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // ExecutorFunction is a type that defines a function to be executed after
@@ -39,12 +43,30 @@ func (args *Args) cobraRunE(cmd *cobra.Command, arguments []string) error {
 	return args.runE(arguments)
 }
 
+// AddCommand adds a subcommand named use which executes runE instead of the
+// root command's function when selected on the command line.
+func (args *Args) AddCommand(use string, short string, runE ExecutorFunction) {
+	args.cmd.AddCommand(&cobra.Command{
+		Use:   use,
+		Short: short,
+		RunE: func(cmd *cobra.Command, arguments []string) error {
+			return runE(arguments)
+		},
+	})
+}
+
 // Execute uses the args and run through the command tree finding appropriate
 // matches for commands and then corresponding flags.
 func (args *Args) Execute() error {
 	return args.cmd.Execute()
 }
 
+// SetOutput sets the writer receiving the usage, help and error messages
+// printed while parsing the arguments, stderr by default.
+func (args *Args) SetOutput(writer io.Writer) {
+	args.cmd.SetOutput(writer)
+}
+
 // Help prints out the help for the command.
 func (args *Args) Help() error {
 	return args.cmd.Help()
@@ -80,6 +102,209 @@ func (args *Args) Uint64VarP(p *uint64, name, shorthand string, envKey string, d
 	args.cmd.Flags().Uint64VarP(p, name, shorthand, envValue, usage)
 }
 
+// Uint32VarP reads a uint32 argument from the command line arguments or the
+// program's environment. defaultValue is used if none is present or an invalid
+// value is present in the environment.
+func (args *Args) Uint32VarP(p *uint32, name, shorthand string, envKey string, defaultValue uint32, usage string) {
+	var envValue uint32
+	envStrValue, ok := os.LookupEnv(envKey)
+	if !ok {
+		envValue = defaultValue
+	} else {
+		parsedValue, err := strconv.ParseUint(envStrValue, 10, 32)
+		if err == nil {
+			envValue = uint32(parsedValue)
+		} else {
+			envValue = defaultValue
+		}
+	}
+	args.cmd.Flags().Uint32VarP(p, name, shorthand, envValue, usage)
+}
+
+// IntVarP reads an int argument from the command line arguments or the
+// program's environment. defaultValue is used if none is present or an invalid
+// value is present in the environment.
+func (args *Args) IntVarP(p *int, name, shorthand string, envKey string, defaultValue int, usage string) {
+	var envValue int
+	envStrValue, ok := os.LookupEnv(envKey)
+	if !ok {
+		envValue = defaultValue
+	} else {
+		parsedValue, err := strconv.ParseInt(envStrValue, 10, 0)
+		if err == nil {
+			envValue = int(parsedValue)
+		} else {
+			envValue = defaultValue
+		}
+	}
+	args.cmd.Flags().IntVarP(p, name, shorthand, envValue, usage)
+}
+
+// Int32VarP reads an int32 argument from the command line arguments or the
+// program's environment. defaultValue is used if none is present or an invalid
+// value is present in the environment.
+func (args *Args) Int32VarP(p *int32, name, shorthand string, envKey string, defaultValue int32, usage string) {
+	var envValue int32
+	envStrValue, ok := os.LookupEnv(envKey)
+	if !ok {
+		envValue = defaultValue
+	} else {
+		parsedValue, err := strconv.ParseInt(envStrValue, 10, 32)
+		if err == nil {
+			envValue = int32(parsedValue)
+		} else {
+			envValue = defaultValue
+		}
+	}
+	args.cmd.Flags().Int32VarP(p, name, shorthand, envValue, usage)
+}
+
+// Int64VarP reads an int64 argument from the command line arguments or the
+// program's environment. defaultValue is used if none is present or an invalid
+// value is present in the environment.
+func (args *Args) Int64VarP(p *int64, name, shorthand string, envKey string, defaultValue int64, usage string) {
+	var envValue int64
+	envStrValue, ok := os.LookupEnv(envKey)
+	if !ok {
+		envValue = defaultValue
+	} else {
+		parsedValue, err := strconv.ParseInt(envStrValue, 10, 64)
+		if err == nil {
+			envValue = parsedValue
+		} else {
+			envValue = defaultValue
+		}
+	}
+	args.cmd.Flags().Int64VarP(p, name, shorthand, envValue, usage)
+}
+
+// Float64VarP reads a float64 argument from the command line arguments or the
+// program's environment. defaultValue is used if none is present or an invalid
+// value is present in the environment.
+func (args *Args) Float64VarP(p *float64, name, shorthand string, envKey string, defaultValue float64, usage string) {
+	var envValue float64
+	envStrValue, ok := os.LookupEnv(envKey)
+	if !ok {
+		envValue = defaultValue
+	} else {
+		parsedValue, err := strconv.ParseFloat(envStrValue, 64)
+		if err == nil {
+			envValue = parsedValue
+		} else {
+			envValue = defaultValue
+		}
+	}
+	args.cmd.Flags().Float64VarP(p, name, shorthand, envValue, usage)
+}
+
+// Float32VarP reads a float32 argument from the command line arguments or the
+// program's environment. defaultValue is used if none is present or an invalid
+// value is present in the environment.
+func (args *Args) Float32VarP(p *float32, name, shorthand string, envKey string, defaultValue float32, usage string) {
+	var envValue float32
+	envStrValue, ok := os.LookupEnv(envKey)
+	if !ok {
+		envValue = defaultValue
+	} else {
+		parsedValue, err := strconv.ParseFloat(envStrValue, 32)
+		if err == nil {
+			envValue = float32(parsedValue)
+		} else {
+			envValue = defaultValue
+		}
+	}
+	args.cmd.Flags().Float32VarP(p, name, shorthand, envValue, usage)
+}
+
+// DurationVarP reads a time.Duration argument from the command line arguments
+// or the program's environment. defaultValue is used if none is present or an
+// invalid value is present in the environment.
+func (args *Args) DurationVarP(p *time.Duration, name, shorthand string, envKey string, defaultValue time.Duration, usage string) {
+	var envValue time.Duration
+	envStrValue, ok := os.LookupEnv(envKey)
+	if !ok {
+		envValue = defaultValue
+	} else {
+		parsedValue, err := time.ParseDuration(envStrValue)
+		if err == nil {
+			envValue = parsedValue
+		} else {
+			envValue = defaultValue
+		}
+	}
+	args.cmd.Flags().DurationVarP(p, name, shorthand, envValue, usage)
+}
+
+// StringSliceVarP reads a comma separated list of strings from the command line
+// arguments or the program's environment. Elements containing a comma can be
+// enclosed in double quotes, the elements are trimmed and the empty ones
+// skipped. defaultValue is used if none is present or an invalid value is
+// present in the environment.
+func (args *Args) StringSliceVarP(p *[]string, name, shorthand string, envKey string, defaultValue []string, usage string) {
+	envValue := defaultValue
+	envStrValue, ok := os.LookupEnv(envKey)
+	if ok {
+		parsedValue, err := parseStringSlice(envStrValue)
+		if err == nil {
+			envValue = parsedValue
+		}
+	}
+	*p = envValue
+	args.cmd.Flags().VarP(&stringSliceValue{value: p}, name, shorthand, usage)
+}
+
+// stringSliceValue is the flag value of StringSliceVarP, parsing the command
+// line value like the environment value. Repeating the flag appends to the
+// list.
+type stringSliceValue struct {
+	value   *[]string
+	changed bool
+}
+
+func (slice *stringSliceValue) Set(value string) error {
+	parsedValue, err := parseStringSlice(value)
+	if err != nil {
+		return err
+	}
+	if slice.changed {
+		parsedValue = append(*slice.value, parsedValue...)
+	}
+	*slice.value = parsedValue
+	slice.changed = true
+	return nil
+}
+
+func (slice *stringSliceValue) Type() string {
+	return "stringSlice"
+}
+
+func (slice *stringSliceValue) String() string {
+	var builder strings.Builder
+	writer := csv.NewWriter(&builder)
+	_ = writer.Write(*slice.value)
+	writer.Flush()
+	return "[" + strings.TrimSuffix(builder.String(), "\n") + "]"
+}
+
+// parseStringSlice parses a comma separated list of strings, trimming the
+// elements and skipping the empty ones
+func parseStringSlice(valueStr string) ([]string, error) {
+	reader := csv.NewReader(strings.NewReader(strings.TrimSpace(valueStr)))
+	reader.TrimLeadingSpace = true
+	record, err := reader.Read()
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	values := []string{}
+	for _, value := range record {
+		if value = strings.TrimSpace(value); len(value) > 0 {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
 // BoolVarP reads a uint64 argument from the command line arguments or the
 // program's environment. defaultValue is used if none is present or an invalid
 // value is present in the environment.
@@ -99,6 +324,17 @@ func (args *Args) BoolVarP(p *bool, name, shorthand string, envKey string, defau
 	args.cmd.Flags().BoolVarP(p, name, shorthand, envValue, usage)
 }
 
+// PersistentStringVarP reads a string argument, available to the command and
+// all its subcommands, from the command line arguments only.
+func (args *Args) PersistentStringVarP(p *string, name, shorthand string, defaultValue string, usage string) {
+	args.cmd.PersistentFlags().StringVarP(p, name, shorthand, defaultValue, usage)
+}
+
+// Changed returns true if the argument name was set on the command line.
+func (args *Args) Changed(name string) bool {
+	return args.cmd.Flags().Changed(name)
+}
+
 func (args *Args) SetArgs(newArgs []string) {
 	args.cmd.SetArgs(newArgs)
 }
@@ -1,6 +1,7 @@
 package args
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"os"
@@ -198,6 +199,175 @@ func TestArgs_ExecuteError(t *testing.T) {
 	assert.Equal(t, "test error", err.Error())
 }
 
+// Test negative values for signed integer arguments
+func TestArgs_ExecuteSignedIntegers(t *testing.T) {
+	var intValue int
+	var int64Value int64
+	_ = os.Setenv("ENV_INT64", "-42")
+	defer os.Unsetenv("ENV_INT64")
+
+	arguments := NewArgs("use", "short", func(strings []string) error {
+		return nil
+	})
+	arguments.IntVarP(&intValue, "int", "n", "ENV_INT", 10, "Use int")
+	arguments.Int64VarP(&int64Value, "int64", "l", "ENV_INT64", 20, "Use int64")
+	arguments.SetArgs([]string{"--int=-5"})
+
+	err := arguments.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, -5, intValue)
+	assert.Equal(t, int64(-42), int64Value)
+
+	arguments = NewArgs("use", "short", func(strings []string) error {
+		return nil
+	})
+	arguments.IntVarP(&intValue, "int", "n", "ENV_INT", 10, "Use int")
+	arguments.Int64VarP(&int64Value, "int64", "l", "ENV_INT64", 20, "Use int64")
+	arguments.SetArgs([]string{"-n", "-7", "--int64", "-9"})
+
+	err = arguments.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, -7, intValue)
+	assert.Equal(t, int64(-9), int64Value)
+}
+
+// Test string slice from the environment
+func TestArgs_ExecuteStringSliceEnvironment(t *testing.T) {
+	var sliceValue []string
+	_ = os.Setenv("ENV_SLICE", ` a, "b,c" `)
+	defer os.Unsetenv("ENV_SLICE")
+
+	arguments := NewArgs("use", "short", func(strings []string) error {
+		return nil
+	})
+	arguments.StringSliceVarP(&sliceValue, "slice", "l", "ENV_SLICE", []string{"default"}, "Use slice")
+	arguments.SetArgs([]string{})
+
+	err := arguments.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b,c"}, sliceValue)
+}
+
+// Test string slice from the command line is trimmed like the environment one
+func TestArgs_ExecuteStringSliceArgs(t *testing.T) {
+	var sliceValue []string
+	_ = os.Setenv("ENV_SLICE", "a, b ,c")
+	defer os.Unsetenv("ENV_SLICE")
+
+	arguments := NewArgs("use", "short", func(strings []string) error {
+		return nil
+	})
+	arguments.StringSliceVarP(&sliceValue, "slice", "l", "ENV_SLICE", []string{"default"}, "Use slice")
+	assert.Equal(t, []string{"a", "b", "c"}, sliceValue)
+
+	arguments.SetArgs([]string{"--slice", ` d , "e,f",`, "-l", "g "})
+	err := arguments.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"d", "e,f", "g"}, sliceValue)
+}
+
+// Test 32-bit integers, an out of range value is ignored in the environment
+// and rejected on the command line
+func TestArgs_Execute32BitIntegers(t *testing.T) {
+	var uint32Value uint32
+	var int32Value int32
+	_ = os.Setenv("ENV_UINT32", "4294967295")
+	_ = os.Setenv("ENV_INT32", "2147483648")
+	defer os.Unsetenv("ENV_UINT32")
+	defer os.Unsetenv("ENV_INT32")
+
+	arguments := NewArgs("use", "short", func(strings []string) error {
+		return nil
+	})
+	arguments.Uint32VarP(&uint32Value, "uint32", "u", "ENV_UINT32", 10, "Use uint32")
+	arguments.Int32VarP(&int32Value, "int32", "n", "ENV_INT32", 20, "Use int32")
+	arguments.SetArgs([]string{})
+
+	err := arguments.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(4294967295), uint32Value)
+	// The environment value overflows, the default is used
+	assert.Equal(t, int32(20), int32Value)
+
+	arguments = NewArgs("use", "short", func(strings []string) error {
+		return nil
+	})
+	arguments.Uint32VarP(&uint32Value, "uint32", "u", "ENV_UINT32", 10, "Use uint32")
+	arguments.Int32VarP(&int32Value, "int32", "n", "ENV_INT32", 20, "Use int32")
+	arguments.SetArgs([]string{"--uint32", "4294967296"})
+
+	err = arguments.Execute()
+
+	assert.NotNil(t, err)
+}
+
+// Test detection of arguments set on the command line
+func TestArgs_Changed(t *testing.T) {
+	argValues := &argumentValues{}
+	ClearEnvironment()
+	_ = os.Setenv(uint64EnvVar, strconv.FormatUint(uint64Arg, 10))
+
+	arguments := NewArgs("use", "short", func(strings []string) error {
+		return nil
+	})
+	setupArgs(arguments, argValues)
+	arguments.SetArgs([]string{"-s", stringArg})
+
+	err := arguments.Execute()
+	ClearEnvironment()
+
+	assert.Nil(t, err)
+	assert.True(t, arguments.Changed("str"))
+	assert.False(t, arguments.Changed("uint64"))
+	assert.False(t, arguments.Changed("bool"))
+	assert.False(t, arguments.Changed("unknown"))
+}
+
+// Test persistent arguments
+func TestArgs_PersistentStringVarP(t *testing.T) {
+	var value string
+	arguments := NewArgs("use", "short", func(strings []string) error {
+		return nil
+	})
+	arguments.PersistentStringVarP(&value, "persistent", "p", "default", "Use persistent")
+	arguments.AddCommand("sub", "sub short", func(strings []string) error {
+		return nil
+	})
+	arguments.SetArgs([]string{"sub", "--persistent", stringArg})
+
+	err := arguments.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, stringArg, value)
+}
+
+// Test subcommand execution
+func TestArgs_AddCommand(t *testing.T) {
+	var rootExecuted, subExecuted bool
+	ClearEnvironment()
+
+	arguments := NewArgs("use", "short", func(strings []string) error {
+		rootExecuted = true
+		return nil
+	})
+	arguments.AddCommand("sub", "sub short", func(strings []string) error {
+		subExecuted = true
+		return nil
+	})
+	arguments.SetArgs([]string{"sub"})
+
+	err := arguments.Execute()
+
+	assert.Nil(t, err)
+	assert.False(t, rootExecuted)
+	assert.True(t, subExecuted)
+}
+
 // TestHelp makes sure the help command-line argument is set
 func TestArgs_Help(t *testing.T) {
 	argValues := &argumentValues{}
@@ -229,3 +399,17 @@ func ClearEnvironment() {
 	_ = os.Unsetenv(uint64EnvVar)
 	_ = os.Unsetenv(boolEnvVar)
 }
+
+func TestArgs_SetOutput(t *testing.T) {
+	var output bytes.Buffer
+	arguments := NewArgs("use", "short", func(strings []string) error {
+		return nil
+	})
+	arguments.SetOutput(&output)
+	arguments.SetArgs([]string{"--unknown"})
+
+	err := arguments.Execute()
+
+	assert.NotNil(t, err)
+	assert.Contains(t, output.String(), "Error: unknown flag: --unknown")
+}
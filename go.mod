@@ -0,0 +1,30 @@
+module github.com/sensu/sensu-enterprise-go-plugin
+
+go 1.13
+
+require (
+	github.com/BurntSushi/toml v0.3.1
+	github.com/coreos/etcd v3.3.12+incompatible
+	github.com/davecgh/go-spew v1.1.1
+	github.com/dgrijalva/jwt-go v3.2.0+incompatible
+	github.com/echlebek/timeproxy v1.0.0
+	github.com/ghodss/yaml v1.0.0
+	github.com/gogo/protobuf v1.2.1
+	github.com/golang/protobuf v1.3.1
+	github.com/inconshreveable/mousetrap v1.0.0
+	github.com/json-iterator/go v0.0.0-20171115153421-f7279a603ede
+	github.com/pmezard/go-difflib v1.0.0
+	github.com/robertkrimen/otto v0.0.0-20180617131154-15f95af6e78d
+	github.com/robfig/cron v1.0.1-0.20171101201047-2315d5715e36
+	github.com/sensu/sensu-go v0.0.0-20181130214924-2daf9d442dee
+	github.com/spf13/cobra v0.0.3
+	github.com/spf13/pflag v1.0.3
+	github.com/stretchr/testify v1.3.0
+	golang.org/x/net v0.0.0-20190419010253-1f3472d942ba
+	golang.org/x/sys v0.0.0-20190418153312-f0ce4c0180be
+	golang.org/x/text v0.3.0
+	google.golang.org/genproto v0.0.0-20190418145605-e7d98fc518a7
+	google.golang.org/grpc v1.20.1
+	gopkg.in/sourcemap.v1 v1.0.5
+	gopkg.in/yaml.v2 v2.4.0 // indirect
+)
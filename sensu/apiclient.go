@@ -0,0 +1,94 @@
+package sensu
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/sensu/sensu-go/types"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Environment variables read by NewSensuAPIClient
+const (
+	SensuAPIURLEnv        = "SENSU_API_URL"
+	SensuAPIKeyEnv        = "SENSU_API_KEY"
+	SensuTrustedCAFileEnv = "SENSU_TRUSTED_CA_FILE"
+)
+
+// DefaultSensuAPITimeout is the timeout of the clients returned by
+// NewSensuAPIClient
+var DefaultSensuAPITimeout = 10 * time.Second
+
+// NewSensuAPIClient returns an HTTP client authenticating to the Sensu backend
+// API with the API key found in SENSU_API_KEY, along with the base URL of the
+// API read from SENSU_API_URL. When SENSU_TRUSTED_CA_FILE is set the backend
+// certificate is verified against the CA bundle it names. The environment
+// variables of the event check, when event is not nil, take precedence over
+// the environment of the handler.
+func NewSensuAPIClient(event *types.Event) (*http.Client, string, error) {
+	baseURL := strings.TrimSuffix(sensuAPIEnv(event, SensuAPIURLEnv), "/")
+	if len(baseURL) == 0 {
+		return nil, "", fmt.Errorf("%s is not set", SensuAPIURLEnv)
+	}
+
+	apiKey := sensuAPIEnv(event, SensuAPIKeyEnv)
+	if len(apiKey) == 0 {
+		return nil, "", fmt.Errorf("%s is not set", SensuAPIKeyEnv)
+	}
+
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if caFile := sensuAPIEnv(event, SensuTrustedCAFileEnv); len(caFile) > 0 {
+		caCerts, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read the CA bundle %s: %s", caFile, err)
+		}
+		caPool := x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(caCerts) {
+			return nil, "", fmt.Errorf("no certificate found in the CA bundle %s", caFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: caPool}
+	}
+
+	client := &http.Client{
+		Timeout:   DefaultSensuAPITimeout,
+		Transport: &apiKeyTransport{apiKey: apiKey, transport: transport},
+	}
+	return client, baseURL, nil
+}
+
+// sensuAPIEnv returns the value of the environment variable name set in the
+// env_vars of the event check, the last one winning, or else in the
+// environment of the handler
+func sensuAPIEnv(event *types.Event, name string) string {
+	if event != nil && event.Check != nil {
+		for i := len(event.Check.EnvVars) - 1; i >= 0; i-- {
+			if strings.HasPrefix(event.Check.EnvVars[i], name+"=") {
+				return strings.TrimPrefix(event.Check.EnvVars[i], name+"=")
+			}
+		}
+	}
+	return os.Getenv(name)
+}
+
+// apiKeyTransport sets the Sensu API key authorization header on the requests
+type apiKeyTransport struct {
+	apiKey    string
+	transport http.RoundTripper
+}
+
+func (apiTransport *apiKeyTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request, set the header on a copy
+	authRequest := new(http.Request)
+	*authRequest = *request
+	authRequest.Header = make(http.Header, len(request.Header)+1)
+	for key, values := range request.Header {
+		authRequest.Header[key] = values
+	}
+	authRequest.Header.Set("Authorization", "Key "+apiTransport.apiKey)
+
+	return apiTransport.transport.RoundTrip(authRequest)
+}
@@ -0,0 +1,139 @@
+package sensu
+
+import (
+	"encoding/pem"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+type recordingTransport struct {
+	requests []*http.Request
+}
+
+func (transport *recordingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	transport.requests = append(transport.requests, request)
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: request}, nil
+}
+
+func clearSensuAPIEnvironment() {
+	_ = os.Unsetenv(SensuAPIURLEnv)
+	_ = os.Unsetenv(SensuAPIKeyEnv)
+	_ = os.Unsetenv(SensuTrustedCAFileEnv)
+}
+
+func TestNewSensuAPIClient(t *testing.T) {
+	clearSensuAPIEnvironment()
+	defer clearSensuAPIEnvironment()
+	_ = os.Setenv(SensuAPIURLEnv, "https://sensu.example.com:8080/")
+	_ = os.Setenv(SensuAPIKeyEnv, "83abef1e-e7d7-4beb-91fc-79ad90084d5b")
+
+	client, baseURL, err := NewSensuAPIClient(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://sensu.example.com:8080", baseURL)
+	assert.Equal(t, DefaultSensuAPITimeout, client.Timeout)
+
+	recorder := &recordingTransport{}
+	client.Transport.(*apiKeyTransport).transport = recorder
+	request, err := http.NewRequest(http.MethodGet, baseURL+"/api/core/v2/namespaces", nil)
+	assert.Nil(t, err)
+	_, err = client.Do(request)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 1, len(recorder.requests))
+	assert.Equal(t, "Key 83abef1e-e7d7-4beb-91fc-79ad90084d5b", recorder.requests[0].Header.Get("Authorization"))
+	assert.Empty(t, request.Header.Get("Authorization"))
+}
+
+// Test the env_vars of the event check take precedence over the environment
+func TestNewSensuAPIClient_CheckEnvVars(t *testing.T) {
+	clearSensuAPIEnvironment()
+	defer clearSensuAPIEnvironment()
+	_ = os.Setenv(SensuAPIURLEnv, "https://sensu.example.com:8080")
+	_ = os.Setenv(SensuAPIKeyEnv, "handler-key")
+
+	event := &types.Event{Check: &types.Check{EnvVars: []string{
+		"SENSU_API_URL=https://other.example.com:8080/",
+		"SENSU_API_KEY_FILE=/etc/sensu/key",
+		"SENSU_API_KEY=check-key",
+	}}}
+	client, baseURL, err := NewSensuAPIClient(event)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://other.example.com:8080", baseURL)
+	assert.Equal(t, "check-key", client.Transport.(*apiKeyTransport).apiKey)
+
+	// The variables the check does not set are read from the environment
+	event.Check.EnvVars = []string{"SENSU_API_URL=https://other.example.com:8080"}
+	client, baseURL, err = NewSensuAPIClient(event)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://other.example.com:8080", baseURL)
+	assert.Equal(t, "handler-key", client.Transport.(*apiKeyTransport).apiKey)
+
+	// An event without check uses the environment
+	client, baseURL, err = NewSensuAPIClient(&types.Event{})
+	assert.Nil(t, err)
+	assert.Equal(t, "https://sensu.example.com:8080", baseURL)
+	assert.Equal(t, "handler-key", client.Transport.(*apiKeyTransport).apiKey)
+}
+
+func TestNewSensuAPIClient_TrustedCAFile(t *testing.T) {
+	clearSensuAPIEnvironment()
+	defer clearSensuAPIEnvironment()
+	var authorization string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		authorization = request.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	caFile, err := ioutil.TempFile("", "sensu-ca")
+	assert.Nil(t, err)
+	defer os.Remove(caFile.Name())
+	assert.Nil(t, pem.Encode(caFile, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	assert.Nil(t, caFile.Close())
+
+	_ = os.Setenv(SensuAPIURLEnv, server.URL)
+	_ = os.Setenv(SensuAPIKeyEnv, "secret-key")
+	_ = os.Setenv(SensuTrustedCAFileEnv, caFile.Name())
+	client, baseURL, err := NewSensuAPIClient(nil)
+	assert.Nil(t, err)
+
+	response, err := client.Get(baseURL + "/health")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	_ = response.Body.Close()
+	assert.Equal(t, "Key secret-key", authorization)
+}
+
+func TestNewSensuAPIClient_Errors(t *testing.T) {
+	tests := []struct {
+		url         string
+		apiKey      string
+		caFile      string
+		expectedErr string
+	}{
+		{"", "key", "", "SENSU_API_URL is not set"},
+		{"https://sensu.example.com:8080", "", "", "SENSU_API_KEY is not set"},
+		{"https://sensu.example.com:8080", "key", "test/missing-ca.pem",
+			"failed to read the CA bundle test/missing-ca.pem: open test/missing-ca.pem: no such file or directory"},
+		{"https://sensu.example.com:8080", "key", "test/event-no-override.json",
+			"no certificate found in the CA bundle test/event-no-override.json"},
+	}
+
+	defer clearSensuAPIEnvironment()
+	for _, test := range tests {
+		clearSensuAPIEnvironment()
+		_ = os.Setenv(SensuAPIURLEnv, test.url)
+		_ = os.Setenv(SensuAPIKeyEnv, test.apiKey)
+		if len(test.caFile) > 0 {
+			_ = os.Setenv(SensuTrustedCAFileEnv, test.caFile)
+		}
+		client, baseURL, err := NewSensuAPIClient(nil)
+		assert.EqualError(t, err, test.expectedErr)
+		assert.Nil(t, client)
+		assert.Empty(t, baseURL)
+	}
+}
@@ -0,0 +1,53 @@
+package sensu
+
+import (
+	"fmt"
+	"strings"
+)
+
+// azureKeyVaultPrefix marks option values referencing an Azure Key Vault
+// secret, as in azurekv:vaultname/secretname
+const azureKeyVaultPrefix = "azurekv:"
+
+// AzureKeyVaultClient retrieves secrets from Azure Key Vault. It is provided by
+// the plugin, typically built with the Azure SDK and the credentials found in
+// the environment, so the library does not depend on the SDK.
+type AzureKeyVaultClient interface {
+	GetSecret(vaultName string, secretName string) (string, error)
+}
+
+// SetAzureKeyVaultClient sets the client used to resolve the string options
+// whose value is an azurekv:vaultname/secretname reference.
+func (resolver *optionResolver) SetAzureKeyVaultClient(client AzureKeyVaultClient) {
+	resolver.azureKeyVaultClient = client
+}
+
+// resolveAzureKeyVaultSecrets replaces the azurekv: references in the string
+// options by the secrets they point to
+func resolveAzureKeyVaultSecrets(client AzureKeyVaultClient, options []*HandlerConfigOption) error {
+	for _, opt := range options {
+		valuePtr, ok := opt.Value.(*string)
+		if !ok || !strings.HasPrefix(*valuePtr, azureKeyVaultPrefix) {
+			continue
+		}
+
+		reference := strings.TrimPrefix(*valuePtr, azureKeyVaultPrefix)
+		parts := strings.SplitN(reference, "/", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			return fmt.Errorf("invalid Azure Key Vault reference %q for option %s, expected %svaultname/secretname",
+				*valuePtr, opt.Argument, azureKeyVaultPrefix)
+		}
+
+		if client == nil {
+			return fmt.Errorf("option %s references Azure Key Vault but no client is set", opt.Argument)
+		}
+
+		secret, err := client.GetSecret(parts[0], parts[1])
+		if err != nil {
+			return fmt.Errorf("failed to read secret %s from Azure Key Vault %s for option %s: %s",
+				parts[1], parts[0], opt.Argument, err)
+		}
+		*valuePtr = secret
+	}
+	return nil
+}
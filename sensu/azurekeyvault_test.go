@@ -0,0 +1,89 @@
+package sensu
+
+import (
+	"fmt"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type fakeAzureKeyVaultClient struct {
+	secrets map[string]string
+	calls   []string
+}
+
+func (client *fakeAzureKeyVaultClient) GetSecret(vaultName string, secretName string) (string, error) {
+	client.calls = append(client.calls, vaultName+"/"+secretName)
+	secret, ok := client.secrets[vaultName+"/"+secretName]
+	if !ok {
+		return "", fmt.Errorf("SecretNotFound")
+	}
+	return secret, nil
+}
+
+func TestResolveAzureKeyVaultSecrets(t *testing.T) {
+	client := &fakeAzureKeyVaultClient{secrets: map[string]string{"myvault/api-token": "s3cr3t"}}
+	tokenValue := "azurekv:myvault/api-token"
+	plainValue := "plain"
+	options := []*HandlerConfigOption{
+		{Argument: "token", Value: &tokenValue},
+		{Argument: "plain", Value: &plainValue},
+	}
+
+	err := resolveAzureKeyVaultSecrets(client, options)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "s3cr3t", tokenValue)
+	assert.Equal(t, "plain", plainValue)
+	assert.Equal(t, []string{"myvault/api-token"}, client.calls)
+}
+
+func TestResolveAzureKeyVaultSecrets_Errors(t *testing.T) {
+	client := &fakeAzureKeyVaultClient{secrets: map[string]string{}}
+	tests := []struct {
+		value       string
+		client      AzureKeyVaultClient
+		expectedErr string
+	}{
+		{"azurekv:myvault/missing", client,
+			"failed to read secret missing from Azure Key Vault myvault for option token: SecretNotFound"},
+		{"azurekv:myvault", client,
+			`invalid Azure Key Vault reference "azurekv:myvault" for option token, expected azurekv:vaultname/secretname`},
+		{"azurekv:/secret", client,
+			`invalid Azure Key Vault reference "azurekv:/secret" for option token, expected azurekv:vaultname/secretname`},
+		{"azurekv:myvault/api-token", nil, "option token references Azure Key Vault but no client is set"},
+	}
+
+	for _, test := range tests {
+		value := test.value
+		options := []*HandlerConfigOption{{Argument: "token", Value: &value}}
+		err := resolveAzureKeyVaultSecrets(test.client, options)
+		assert.EqualError(t, err, test.expectedErr)
+		assert.Equal(t, test.value, value)
+	}
+}
+
+func TestGoHandler_Execute_AzureKeyVault(t *testing.T) {
+	var executeValue string
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			executeValue = values.arg1
+			return nil
+		})
+	goHandler.SetAzureKeyVaultClient(&fakeAzureKeyVaultClient{secrets: map[string]string{"myvault/arg1": "s3cr3t"}})
+	goHandler.cmdArgs.SetArgs([]string{"--arg1", "azurekv:myvault/arg1"})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "s3cr3t", executeValue)
+}
@@ -0,0 +1,131 @@
+package sensu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sensu/sensu-go/types"
+)
+
+// isEventBatch tells whether the event data is a JSON array of events
+func isEventBatch(eventData []byte, inputFormat string) bool {
+	eventData = bytes.TrimLeft(eventData, " \t\r\n")
+	return inputFormat == InputFormatJSON && len(eventData) > 0 && eventData[0] == '['
+}
+
+// splitEventLines splits newline-delimited JSON event data into the data of
+// each event, skipping the blank lines
+func splitEventLines(eventData []byte) []json.RawMessage {
+	var eventsData []json.RawMessage
+	for _, line := range bytes.Split(eventData, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			eventsData = append(eventsData, line)
+		}
+	}
+	return eventsData
+}
+
+// runBatch runs the handler for every event of a JSON array of events or of
+// newline-delimited JSON events. The options are resolved from the command line
+// and the environment once, the overrides of an event only apply to that event.
+// The errors of the events are aggregated, an invalid event does not prevent
+// the others from executing unless BatchFailFast is set. The duplicate events
+// are skipped when DedupBatch is set.
+func (goHandler *GoHandler) runBatch(ctx context.Context, eventsData []json.RawMessage) error {
+	err := goHandler.resolveCommandLine()
+	if err != nil {
+		return err
+	}
+
+	var duplicates map[int]bool
+	if goHandler.config.DedupBatch {
+		duplicates = duplicateEvents(eventsData)
+	}
+
+	restoreOptions := snapshotOptions(goHandler.options)
+	var errs []error
+	for i, eventData := range eventsData {
+		if duplicates[i] {
+			goHandler.log(LogLevelDebug, "duplicate event skipped", map[string]interface{}{
+				"index": i + 1,
+			})
+			continue
+		}
+		restoreOptions()
+		if err = goHandler.runBatchEvent(ctx, eventData); err != nil {
+			errs = append(errs, fmt.Errorf("event %d: %w", i+1, err))
+			if goHandler.config.BatchFailFast {
+				break
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return &MultiError{Errors: errs}
+	}
+
+	return nil
+}
+
+// duplicateEvents returns the indexes of the events of a batch superseded by
+// an event with the same namespace, entity and check and a later timestamp, or
+// the same timestamp and a later position in the batch. The events that do not
+// unmarshal are kept so that their error is reported.
+func duplicateEvents(eventsData []json.RawMessage) map[int]bool {
+	type latestEvent struct {
+		index     int
+		timestamp int64
+	}
+
+	duplicates := map[int]bool{}
+	latest := map[string]latestEvent{}
+	for i, eventData := range eventsData {
+		var sensuEvent *types.Event
+		if err := json.Unmarshal(eventData, &sensuEvent); err != nil || sensuEvent == nil {
+			continue
+		}
+
+		key := sensuEvent.Namespace + "/" + EventKey(sensuEvent)
+		previous, ok := latest[key]
+		if ok && previous.timestamp > sensuEvent.Timestamp {
+			duplicates[i] = true
+			continue
+		}
+		if ok {
+			duplicates[previous.index] = true
+		}
+		latest[key] = latestEvent{index: i, timestamp: sensuEvent.Timestamp}
+	}
+	return duplicates
+}
+
+// runBatchEvent unmarshals and validates one event of a batch and runs the
+// handler for it
+func (goHandler *GoHandler) runBatchEvent(ctx context.Context, eventData json.RawMessage) error {
+	var sensuEvent *types.Event
+	err := json.Unmarshal(eventData, &sensuEvent)
+	if err != nil {
+		return fmt.Errorf("Failed to unmarshal event data: %s", err)
+	}
+	if sensuEvent == nil {
+		return errors.New("event is null")
+	}
+
+	err = checkEvent(sensuEvent, goHandler.config)
+	if err != nil {
+		return err
+	}
+	goHandler.sensuEvent = sensuEvent
+	goHandler.log(LogLevelDebug, "event read", map[string]interface{}{
+		"event": EventKey(goHandler.sensuEvent),
+	})
+
+	err = goHandler.resolveEventAndValidate(ctx)
+	if err != nil {
+		return err
+	}
+
+	return goHandler.executeEvent(ctx)
+}
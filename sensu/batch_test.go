@@ -0,0 +1,234 @@
+package sensu
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// Test an array of events, the invalid event does not prevent the valid one
+// from executing
+func TestGoHandler_Execute_Batch(t *testing.T) {
+	var executedChecks []string
+	clearEnvironment()
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-batch.json", defaultCmdLineArgs,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			executedChecks = append(executedChecks, event.Check.Name)
+			return nil
+		},
+		"value-arg1", uint64(7531), false)
+	assert.EqualError(t, err, "event 2: entity is missing from event")
+	assert.Equal(t, []string{"check-nginx"}, executedChecks)
+}
+
+func TestGoHandler_Execute_BatchOverrides(t *testing.T) {
+	var executeValues []string
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			executeValues = append(executeValues, values.arg1)
+			return nil
+		})
+	overrideEvent, err := ioutil.ReadFile("test/event-check-override.json")
+	assert.Nil(t, err)
+	plainEvent, err := ioutil.ReadFile("test/event-no-override.json")
+	assert.Nil(t, err)
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = strings.NewReader(fmt.Sprintf("\n[%s, %s, null]", overrideEvent, plainEvent))
+	err = goHandler.Execute()
+
+	assert.EqualError(t, err, "event 3: event is null")
+	assert.Equal(t, []string{"value-check1", "Default1"}, executeValues)
+}
+
+// Test only the latest of the duplicate events of a batch is handled with
+// DedupBatch
+func TestGoHandler_Execute_BatchDedup(t *testing.T) {
+	plainEvent, err := ioutil.ReadFile("test/event-no-override.json")
+	assert.Nil(t, err)
+	overrideEvent, err := ioutil.ReadFile("test/event-check-override.json")
+	assert.Nil(t, err)
+	ndjsonEvents, err := ioutil.ReadFile("test/event-batch.ndjson")
+	assert.Nil(t, err)
+	otherEvents := strings.Split(strings.TrimSpace(string(ndjsonEvents)), "\n")[1:]
+	withTimestamp := func(eventData []byte, timestamp string) string {
+		return strings.Replace(string(eventData), "1550816106", timestamp, 1)
+	}
+	eventsData := fmt.Sprintf("[%s, %s, %s, %s]", withTimestamp(plainEvent, "1550816100"),
+		withTimestamp(overrideEvent, "1550816300"), withTimestamp(plainEvent, "1550816200"),
+		strings.Join(otherEvents, ", "))
+
+	tests := []struct {
+		dedupBatch     bool
+		expectedChecks []string
+		expectedValues []string
+	}{
+		{false, []string{"check-nginx", "check-nginx", "check-nginx", "check-postgres", "check-redis"},
+			[]string{"Default1", "value-check1", "Default1", "value-check2", "Default1"}},
+		{true, []string{"check-nginx", "check-postgres", "check-redis"},
+			[]string{"value-check1", "value-check2", "Default1"}},
+	}
+
+	for _, test := range tests {
+		var executedChecks, executeValues []string
+		clearEnvironment()
+		options := getDefaultOptions()
+		values := handlerValues{}
+		options[0].Value = &values.arg1
+		options[1].Value = &values.arg2
+		options[2].Value = &values.arg3
+		config := defaultHandlerConfig
+		config.DedupBatch = test.dedupBatch
+		goHandler := NewGoHandler(&config, options,
+			func(event *types.Event) error {
+				return nil
+			}, func(event *types.Event) error {
+				executedChecks = append(executedChecks, event.Check.Name)
+				executeValues = append(executeValues, values.arg1)
+				return nil
+			})
+		goHandler.cmdArgs.SetArgs([]string{})
+		goHandler.eventReader = strings.NewReader(eventsData)
+		err = goHandler.Execute()
+
+		assert.Nil(t, err)
+		assert.Equal(t, test.expectedChecks, executedChecks)
+		assert.Equal(t, test.expectedValues, executeValues)
+	}
+}
+
+func TestDuplicateEvents(t *testing.T) {
+	eventsData := []json.RawMessage{
+		[]byte(`{"timestamp": 2, "entity": {"metadata": {"name": "web", "namespace": "default"}}, "check": {"metadata": {"name": "disk"}}}`),
+		[]byte(`{"timestamp": 1, "entity": {"metadata": {"name": "web", "namespace": "default"}}, "check": {"metadata": {"name": "disk"}}}`),
+		[]byte(`{"timestamp": 1, "entity": {"metadata": {"name": "web"}}, "check": {"metadata": {"name": "disk"}}, "metadata": {"namespace": "other"}}`),
+		[]byte(`{"timestamp": 2, "entity": {"metadata": {"name": "web", "namespace": "default"}}, "check": {"metadata": {"name": "disk"}}}`),
+		[]byte(`not json`),
+		[]byte(`not json`),
+		[]byte(`null`),
+	}
+
+	// The second event is older than the first one, the first one has the same
+	// timestamp as the fourth one but comes before it, the third one is in
+	// another namespace
+	assert.Equal(t, map[int]bool{0: true, 1: true}, duplicateEvents(eventsData))
+}
+
+// Test newline-delimited events with the --batch flag, the overrides of an
+// event only apply to that event
+func TestGoHandler_Execute_BatchNDJSON(t *testing.T) {
+	var executedChecks, executeValues []string
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			executedChecks = append(executedChecks, event.Check.Name)
+			executeValues = append(executeValues, values.arg1)
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{"--batch"})
+	goHandler.eventReader = getFileReader("test/event-batch.ndjson")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"check-nginx", "check-postgres", "check-redis"}, executedChecks)
+	assert.Equal(t, []string{"Default1", "value-check2", "Default1"}, executeValues)
+}
+
+// Test the errors of newline-delimited events are aggregated, or stop the
+// batch with BatchFailFast
+func TestGoHandler_Execute_BatchNDJSONPartialFailure(t *testing.T) {
+	eventData, err := ioutil.ReadFile("test/event-batch.ndjson")
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(eventData)), "\n")
+	// Blank lines are skipped, an invalid line fails only its event
+	eventData = []byte(lines[0] + "\n\n{invalid\n" + lines[1] + "\n" + lines[2] + "\n")
+
+	for _, failFast := range []bool{false, true} {
+		var executedChecks []string
+		clearEnvironment()
+		handlerConfig := defaultHandlerConfig
+		handlerConfig.BatchMode = true
+		handlerConfig.BatchFailFast = failFast
+		options := getDefaultOptions()
+		values := handlerValues{}
+		options[0].Value = &values.arg1
+		options[1].Value = &values.arg2
+		options[2].Value = &values.arg3
+		goHandler := NewGoHandler(&handlerConfig, options,
+			func(event *types.Event) error {
+				if event.Check.Name == "check-postgres" {
+					return fmt.Errorf("validation error")
+				}
+				return nil
+			}, func(event *types.Event) error {
+				executedChecks = append(executedChecks, event.Check.Name)
+				return nil
+			}, WithErrorWriter(ioutil.Discard))
+		goHandler.cmdArgs.SetArgs([]string{})
+		goHandler.eventReader = bytes.NewReader(eventData)
+		err = goHandler.Execute()
+
+		if failFast {
+			assert.EqualError(t, err, "event 2: Failed to unmarshal event data: invalid character 'i' looking for beginning of object key string")
+			assert.Equal(t, []string{"check-nginx"}, executedChecks)
+		} else {
+			assert.EqualError(t, err, "event 2: Failed to unmarshal event data: invalid character 'i' looking for beginning of object key string; "+
+				"event 3: error validating input: validation error")
+			assert.Equal(t, []string{"check-nginx", "check-redis"}, executedChecks)
+		}
+	}
+}
+
+// Test the errors of a batch keep their type, a validation failure exits with
+// the validation exit code
+func TestGoHandler_Execute_BatchExitCode(t *testing.T) {
+	clearEnvironment()
+	handlerConfig := defaultHandlerConfig
+	handlerConfig.ValidationExitCode = 3
+	handlerConfig.ExecutionExitCode = 4
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+	goHandler := NewGoHandler(&handlerConfig, options,
+		func(event *types.Event) error {
+			if event.Entity == nil || event.Entity.Name != "webserver01" {
+				return nil
+			}
+			return fmt.Errorf("validation error")
+		}, func(event *types.Event) error {
+			return nil
+		}, WithErrorWriter(ioutil.Discard))
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-batch.json")
+	err := goHandler.Execute()
+
+	var multiErr *MultiError
+	assert.True(t, errors.As(err, &multiErr))
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, 3, goHandler.ExitCode(err))
+}
@@ -0,0 +1,182 @@
+package sensu
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/ghodss/yaml"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// sourceConfigFile is the source of the values read from the --config file
+const sourceConfigFile = "config"
+
+// loadConfigFile sets the values of the --config file as a custom source,
+// replacing the values read by a previous Execute. They rank below the command
+// line and above the environment.
+func (resolver *optionResolver) loadConfigFile() error {
+	var values map[string]string
+	if len(resolver.configFile) > 0 {
+		var err error
+		values, err = readConfigFile(resolver.configFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	sources := resolver.customSources[:0]
+	for _, source := range resolver.customSources {
+		if !source.configFile {
+			sources = append(sources, source)
+		}
+	}
+	resolver.customSources = sources
+
+	if values != nil {
+		resolver.customSources = append(resolver.customSources, customSource{
+			name:          sourceConfigFile,
+			values:        values,
+			precedence:    PrecedenceAboveEnv,
+			matchArgument: true,
+			configFile:    true,
+		})
+	}
+	return nil
+}
+
+// readConfigFile reads the option values of a YAML, TOML or INI file, selected
+// by the file extension, keyed by option argument or path
+func readConfigFile(configFile string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %s", err)
+	}
+
+	var document map[string]interface{}
+	switch strings.ToLower(filepath.Ext(configFile)) {
+	case ".yaml", ".yml":
+		document, err = parseYAMLConfig(data)
+	case ".toml":
+		document, err = parseTOMLConfig(data)
+	case ".ini":
+		document, err = parseINIConfig(data)
+	default:
+		return nil, fmt.Errorf("unsupported config file %s, the extension must be .yaml, .yml, .toml or .ini", configFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %s", configFile, err)
+	}
+
+	values := make(map[string]string, len(document))
+	for key, value := range document {
+		stringValue, err := configValueString(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: key %s: %s", configFile, key, err)
+		}
+		values[key] = stringValue
+	}
+	return values, nil
+}
+
+// configValueString formats a value decoded from a config file or a JSON
+// annotation the way setOptionValue parses it: lists as comma separated values
+// and objects as JSON
+func configValueString(value interface{}) (string, error) {
+	switch typedValue := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return typedValue, nil
+	case []interface{}:
+		values := make([]string, 0, len(typedValue))
+		for _, item := range typedValue {
+			itemString, err := configValueString(item)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, itemString)
+		}
+		return formatDefault(values), nil
+	case map[string]interface{}, []map[string]interface{}:
+		jsonValue, err := json.Marshal(typedValue)
+		if err != nil {
+			return "", err
+		}
+		return string(jsonValue), nil
+	case time.Time:
+		return typedValue.Format(time.RFC3339Nano), nil
+	default:
+		return fmt.Sprint(typedValue), nil
+	}
+}
+
+// parseYAMLConfig parses a YAML document, keeping the numbers as written
+func parseYAMLConfig(data []byte) (map[string]interface{}, error) {
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	document := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.UseNumber()
+	if err = decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// parseTOMLConfig parses a TOML document, tables are decoded as objects
+func parseTOMLConfig(data []byte) (map[string]interface{}, error) {
+	document := map[string]interface{}{}
+	if _, err := toml.Decode(string(data), &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// parseINIConfig parses an INI document. The keys before the first section are
+// kept as is, the keys of a section are prefixed with the section name and a
+// dot. Lines starting with ; or # are comments and quoted values are unquoted.
+func parseINIConfig(data []byte) (map[string]interface{}, error) {
+	document := map[string]interface{}{}
+	section := ""
+	for index, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' || len(strings.TrimSpace(line[1:len(line)-1])) == 0 {
+				return nil, fmt.Errorf("line %d: invalid section %s", index+1, line)
+			}
+			section = strings.TrimSpace(line[1:len(line)-1]) + "."
+			continue
+		}
+
+		separator := strings.Index(line, "=")
+		if separator < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value, got %s", index+1, line)
+		}
+		key := strings.TrimSpace(line[:separator])
+		if len(key) == 0 {
+			return nil, fmt.Errorf("line %d: missing key", index+1)
+		}
+		key = section + key
+		if _, ok := document[key]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key %s", index+1, key)
+		}
+
+		value := strings.TrimSpace(line[separator+1:])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		document[key] = value
+	}
+	return document, nil
+}
@@ -0,0 +1,238 @@
+package sensu
+
+import (
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestGoHandler_Execute_ConfigFile(t *testing.T) {
+	for _, configFile := range []string{"test/config.yaml", "test/config.toml", "test/config.ini"} {
+		clearEnvironment()
+		err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-no-override.json",
+			[]string{"--config", configFile},
+			func(event *types.Event) error {
+				return nil
+			}, func(event *types.Event) error {
+				return nil
+			},
+			"value-config1", uint64(2468), true)
+		assert.Nil(t, err, configFile)
+	}
+}
+
+func TestGoHandler_Execute_ConfigFilePrecedence(t *testing.T) {
+	tests := []struct {
+		eventFile      string
+		cmdLineArgs    []string
+		env1           string
+		expectedValue1 string
+		expectedValue2 uint64
+	}{
+		// The config file is above the environment
+		{"test/event-no-override.json", []string{}, "value-env1", "value-config1", 2468},
+		// The command line is above the config file
+		{"test/event-no-override.json", []string{"--arg1", "value-arg1", "--arg2", "7531"}, "", "value-arg1", 7531},
+		// The event overrides are above the config file
+		{"test/event-check-override.json", []string{}, "", "value-check1", 1357},
+	}
+
+	for _, test := range tests {
+		clearEnvironment()
+		if len(test.env1) > 0 {
+			_ = os.Setenv("ENV_1", test.env1)
+		}
+		options := getDefaultOptions()
+		values := handlerValues{}
+		options[0].Value = &values.arg1
+		options[1].Value = &values.arg2
+		options[2].Value = &values.arg3
+		goHandler := NewGoHandler(&defaultHandlerConfig, options, func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+		goHandler.cmdArgs.SetArgs(append([]string{"--config", "test/config.yaml"}, test.cmdLineArgs...))
+		goHandler.eventReader = getFileReader(test.eventFile)
+		err := goHandler.Execute()
+
+		assert.Nil(t, err)
+		assert.Equal(t, test.expectedValue1, values.arg1)
+		assert.Equal(t, test.expectedValue2, values.arg2)
+	}
+	clearEnvironment()
+}
+
+func TestGoHandler_Execute_ConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		configFile  string
+		expectedErr string
+	}{
+		{"test/missing.yaml", "failed to read config file: open test/missing.yaml: no such file or directory"},
+		{"test/event-no-override.json", "unsupported config file test/event-no-override.json, the extension must be .yaml, .yml, .toml or .ini"},
+		{"test/invalid.ini", "failed to parse config file test/invalid.ini: line 3: expected key = value, got arg3"},
+	}
+
+	for _, test := range tests {
+		clearEnvironment()
+		err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-no-override.json",
+			[]string{"--config", test.configFile},
+			func(event *types.Event) error {
+				return nil
+			}, func(event *types.Event) error {
+				return nil
+			},
+			"Default1", uint64(33333), false)
+		assert.EqualError(t, err, test.expectedErr)
+	}
+}
+
+func TestParseTOMLConfig(t *testing.T) {
+	document, err := parseTOMLConfig([]byte(`
+# Comment
+name = "say \"hi\"\t\u00e9" # trailing comment
+path = 'C:\temp'
+"quoted-key" = "value"
+count = 1_000
+ratio = -0.5
+enabled = false
+tags = ["a", 'b,c']
+empty = []
+since = 2019-02-22T06:15:06Z
+point = { x = 1, y = 2 }
+
+[thresholds]
+warning = 80
+critical = 90
+`))
+
+	assert.Nil(t, err)
+	values := map[string]string{}
+	for key, value := range document {
+		values[key], err = configValueString(value)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, map[string]string{
+		"name":       "say \"hi\"\t\u00e9",
+		"path":       `C:\temp`,
+		"quoted-key": "value",
+		"count":      "1000",
+		"ratio":      "-0.5",
+		"enabled":    "false",
+		"tags":       `a,"b,c"`,
+		"empty":      "",
+		"since":      "2019-02-22T06:15:06Z",
+		"point":      `{"x":1,"y":2}`,
+		"thresholds": `{"critical":90,"warning":80}`,
+	}, values)
+}
+
+func TestParseTOMLConfig_Errors(t *testing.T) {
+	for _, document := range []string{
+		"name",
+		"= 1",
+		"name =",
+		`name = "abc`,
+		`name = "\q"`,
+		"name = abc",
+		`tags = ["a", "b"`,
+		"name = 1\nname = 2",
+	} {
+		_, err := parseTOMLConfig([]byte(document))
+		assert.NotNil(t, err, document)
+	}
+}
+
+func TestParseINIConfig(t *testing.T) {
+	document, err := parseINIConfig([]byte(`
+; Comment
+# Other comment
+name = say hi
+quoted = " padded "
+single = 'a=b'
+empty =
+
+[thresholds]
+warning = 80
+  critical=90
+`))
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name":                "say hi",
+		"quoted":              " padded ",
+		"single":              "a=b",
+		"empty":               "",
+		"thresholds.warning":  "80",
+		"thresholds.critical": "90",
+	}, document)
+}
+
+func TestParseINIConfig_Errors(t *testing.T) {
+	tests := []struct {
+		document    string
+		expectedErr string
+	}{
+		{"name", "line 1: expected key = value, got name"},
+		{"\n= 1", "line 2: missing key"},
+		{"[section", "line 1: invalid section [section"},
+		{"[ ]", "line 1: invalid section [ ]"},
+		{"name = 1\nname = 2", "line 2: duplicate key name"},
+		{"[a]\nname = 1\n[a]\nname = 2", "line 4: duplicate key a.name"},
+	}
+
+	for _, test := range tests {
+		_, err := parseINIConfig([]byte(test.document))
+		assert.EqualError(t, err, test.expectedErr, test.document)
+	}
+}
+
+// Test the keys of an INI section set the options whose path has the section
+// as prefix
+func TestGoHandler_Execute_ConfigFileINISection(t *testing.T) {
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[1].Path = "thresholds.warning"
+	options[2].Value = &values.arg3
+	goHandler := NewGoHandler(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) error {
+		return nil
+	})
+	goHandler.cmdArgs.SetArgs([]string{"--config", "test/config-sections.ini"})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "value-config1", values.arg1)
+	assert.Equal(t, uint64(80), values.arg2)
+}
+
+// Test executing the handler again replaces the values of the config file
+// instead of adding them to the previous ones
+func TestGoHandler_Execute_ConfigFileReplaced(t *testing.T) {
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+	goHandler := NewGoHandler(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) error {
+		return nil
+	})
+	goHandler.ResolveFrom(map[string]string{"path1": "value-backend1"}, "backend", PrecedenceAboveDefault)
+
+	for i := 0; i < 2; i++ {
+		goHandler.cmdArgs.SetArgs([]string{"--config", "test/config.toml"})
+		goHandler.eventReader = getFileReader("test/event-no-override.json")
+		assert.Nil(t, goHandler.Execute())
+		assert.Equal(t, "value-config1", values.arg1)
+		assert.Len(t, goHandler.customSources, 2)
+	}
+}
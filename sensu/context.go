@@ -0,0 +1,95 @@
+package sensu
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// DefaultShutdownGracePeriod is the grace period used when the HandlerConfig
+// does not set ShutdownGracePeriod
+const DefaultShutdownGracePeriod = 2 * time.Second
+
+// timeoutContext returns a context cancelled after timeout seconds, or a
+// context without deadline if timeout is zero
+func timeoutContext(timeout uint64) (context.Context, context.CancelFunc) {
+	if timeout == 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+}
+
+// notifyTerminationSignals relays the SIGTERM and SIGINT signals to signals
+func notifyTerminationSignals(signals chan<- os.Signal) {
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+}
+
+// signalContext returns a context cancelled with parent or when the process
+// receives SIGTERM or SIGINT, as when the Sensu agent stops the handler. The
+// process exits with status 1 if the handler has not returned within the
+// shutdown grace period after the signal. The returned function, called once
+// the handler returns, cancels the context and stops relaying the signals.
+func (goHandler *GoHandler) signalContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 1)
+	returned := make(chan struct{})
+	goHandler.notifySignals(signals)
+
+	go func() {
+		select {
+		case receivedSignal := <-signals:
+			goHandler.log(LogLevelInfo, "signal received, cancelling the handler", map[string]interface{}{
+				"signal": receivedSignal.String(),
+			})
+			cancel()
+		case <-returned:
+			return
+		}
+
+		gracePeriod := goHandler.config.ShutdownGracePeriod
+		if gracePeriod == 0 {
+			gracePeriod = DefaultShutdownGracePeriod
+		}
+		select {
+		case <-time.After(gracePeriod):
+			goHandler.log(LogLevelError, "handler did not return within the shutdown grace period", map[string]interface{}{
+				"grace_period": gracePeriod.String(),
+			})
+			goHandler.exitFunction(1)
+		case <-returned:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		close(returned)
+		cancel()
+	}
+}
+
+// WarnOnDeadlineOverrun makes the handler write a warning to its error writer,
+// and log it, when the execute function returns more than grace after its
+// context deadline, which shows it does not honour the context. It is a
+// diagnostic aid meant for development.
+func (goHandler *GoHandler) WarnOnDeadlineOverrun(grace time.Duration) {
+	goHandler.deadlineOverrunGrace = grace
+}
+
+// checkDeadlineOverrun warns when the execute function called with ctx
+// returned later than the grace set with WarnOnDeadlineOverrun after the
+// context deadline
+func (goHandler *GoHandler) checkDeadlineOverrun(ctx context.Context) {
+	if goHandler.deadlineOverrunGrace == 0 {
+		return
+	}
+	if deadline, ok := ctx.Deadline(); ok && ctx.Err() != nil {
+		if overrun := time.Since(deadline); overrun > goHandler.deadlineOverrunGrace {
+			goHandler.errorLog().Printf("Warning: execute function returned %s after the context deadline, it may be ignoring the context\n", overrun)
+			goHandler.log(LogLevelError, "execute function ignored the context deadline", map[string]interface{}{
+				"overrun": overrun.String(),
+			})
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package sensu
+
+import (
+	"bytes"
+	"context"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestNewGoHandlerWithContext_Signal(t *testing.T) {
+	var signals chan<- os.Signal
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandlerWithContext(&defaultHandlerConfig, options,
+		func(ctx context.Context, event *types.Event) error {
+			return nil
+		}, func(ctx context.Context, event *types.Event) error {
+			signals <- syscall.SIGTERM
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+				return nil
+			}
+		})
+	goHandler.notifySignals = func(notified chan<- os.Signal) {
+		signals = notified
+	}
+	exitStatus := -1
+	goHandler.exitFunction = func(status int) {
+		exitStatus = status
+	}
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.EqualError(t, err, "error executing handler: context canceled")
+	assert.Equal(t, -1, exitStatus)
+}
+
+func TestNewGoHandlerWithContext_SignalGracePeriod(t *testing.T) {
+	var signals chan<- os.Signal
+	exitStatus := make(chan int, 1)
+	clearEnvironment()
+	handlerConfig := defaultHandlerConfig
+	handlerConfig.ShutdownGracePeriod = 10 * time.Millisecond
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandlerWithContext(&handlerConfig, options,
+		func(ctx context.Context, event *types.Event) error {
+			return nil
+		}, func(ctx context.Context, event *types.Event) error {
+			// Ignore the cancellation until the process would have exited
+			signals <- syscall.SIGTERM
+			select {
+			case status := <-exitStatus:
+				exitStatus <- status
+			case <-time.After(5 * time.Second):
+			}
+			return nil
+		})
+	goHandler.notifySignals = func(notified chan<- os.Signal) {
+		signals = notified
+	}
+	goHandler.exitFunction = func(status int) {
+		exitStatus <- status
+	}
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, <-exitStatus)
+}
+
+func TestTimeoutContext(t *testing.T) {
+	ctx, cancel := timeoutContext(0)
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+	cancel()
+	assert.NotNil(t, ctx.Err())
+
+	ctx, cancel = timeoutContext(10)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(10*time.Second), deadline, time.Second)
+}
+
+func TestGoHandler_WarnOnDeadlineOverrun(t *testing.T) {
+	var errorOutput bytes.Buffer
+	goHandler := NewGoHandlerWithContext(&defaultHandlerConfig, nil, func(ctx context.Context, event *types.Event) error {
+		return nil
+	}, func(ctx context.Context, event *types.Event) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithErrorWriter(&errorOutput))
+	goHandler.WarnOnDeadlineOverrun(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	err := goHandler.execute(ctx)
+	cancel()
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Empty(t, errorOutput.String())
+
+	goHandler.executeFunction = func(ctx context.Context, event *types.Event) error {
+		time.Sleep(200 * time.Millisecond)
+		return nil
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	err = goHandler.execute(ctx)
+	cancel()
+	assert.Nil(t, err)
+	assert.Contains(t, errorOutput.String(), "after the context deadline, it may be ignoring the context")
+}
@@ -0,0 +1,93 @@
+package sensu
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// ValidateOnly runs the handler like Execute, parsing the command line, reading
+// the event, resolving the options and running the option validators and the
+// validation function, but never calls the execute function. The first error
+// is returned and, unlike Execute, a StatusError does not exit the process.
+func (goHandler *GoHandler) ValidateOnly() error {
+	goHandler.validateOnly = true
+	defer func() {
+		goHandler.validateOnly = false
+	}()
+
+	err := goHandler.setupOptions()
+	if err != nil {
+		return err
+	}
+
+	return goHandler.cmdArgs.Execute()
+}
+
+// printResolvedValues writes the resolved option values to stdout as JSON
+func (goHandler *GoHandler) printResolvedValues() error {
+	valuesJSON, err := json.Marshal(goHandler.ResolvedValues())
+	if err != nil {
+		return fmt.Errorf("error marshalling the resolved values: %s", err)
+	}
+	_, err = fmt.Fprintln(goHandler.outputWriter, string(valuesJSON))
+	return err
+}
+
+// IsSet returns true if the value of the option with the given Argument was
+// provided by any source, even if it is a zero value, rather than being its
+// Default. It is meaningful once Execute has resolved the options.
+func (goHandler *GoHandler) IsSet(argument string) bool {
+	for _, opt := range goHandler.options {
+		if opt.Argument == argument {
+			return len(opt.source) > 0 && opt.source != sourceDefault
+		}
+	}
+	return false
+}
+
+// ResolvedValues returns the value of every option, keyed by its Argument,
+// once Execute has applied the command line, environment and event overrides.
+// Called before Execute it returns the default values. Options using Parse
+// without a Value map to the resolved string value, options without either
+// are omitted and the value of Secret options is masked.
+func (goHandler *GoHandler) ResolvedValues() map[string]interface{} {
+	// Registering the options sets their default values, an invalid option is
+	// reported by Execute
+	_ = goHandler.setupOptions()
+
+	values := make(map[string]interface{}, len(goHandler.options))
+	for _, opt := range goHandler.options {
+		if opt.Value == nil && opt.Parse == nil {
+			continue
+		}
+		values[opt.Argument] = displayValue(opt, optionValue(opt))
+	}
+	return values
+}
+
+// VerifyAgainst performs a dry run of the event reading, option resolution and
+// validation against eventJSON, without calling the execute function. Options
+// are resolved from the environment, the config file given with WithConfigFile
+// and defaults, not the command line.
+func (goHandler *GoHandler) VerifyAgainst(eventJSON []byte) error {
+	err := goHandler.setupOptions()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := timeoutContext(goHandler.config.Timeout)
+	defer cancel()
+
+	sensuEvent, err := readEvent(bytes.NewReader(eventJSON), goHandler.config, InputFormatJSON)
+	if err != nil {
+		return err
+	}
+
+	err = goHandler.ResolveOptions(sensuEvent)
+	if err != nil {
+		return err
+	}
+
+	return goHandler.validate(ctx)
+}
@@ -0,0 +1,421 @@
+package sensu
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+)
+
+// Test the execute function is not called in dry run mode
+func TestGoHandler_Execute_DryRun(t *testing.T) {
+	var logOutput bytes.Buffer
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			validateCalled = true
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			return nil
+		}, WithErrorWriter(&logOutput), WithOutputWriter(ioutil.Discard))
+	goHandler.cmdArgs.SetArgs([]string{"--dry-run"})
+	goHandler.eventReader = getFileReader("test/event-check-override.json")
+	err := goHandler.Execute()
+	assert.Nil(t, err)
+	assert.True(t, validateCalled)
+	assert.False(t, executeCalled)
+	assert.Equal(t, "value-check1", values.arg1)
+	assert.Contains(t, logOutput.String(), "Dry run, skipping the handler execution")
+
+	// Validation errors are still returned
+	clearEnvironment()
+	err = goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-check-override.json", []string{"--dry-run"},
+		func(event *types.Event) error {
+			return fmt.Errorf("invalid input")
+		}, func(event *types.Event) error {
+			executeCalled = true
+			return nil
+		},
+		"value-check1", uint64(1357), false)
+	assert.EqualError(t, err, "error validating input: invalid input")
+	assert.False(t, executeCalled)
+}
+
+// Test the resolved values are printed in dry run mode
+func TestGoHandler_Execute_DryRunOutput(t *testing.T) {
+	var output bytes.Buffer
+	var executeCalled bool
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			return nil
+		})
+	goHandler.outputWriter = &output
+	goHandler.cmdArgs.SetArgs([]string{"--dry-run", "--arg3"})
+	goHandler.eventReader = getFileReader("test/event-entity-override.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.False(t, executeCalled)
+	assert.Equal(t, `{"arg1":"value-entity1","arg2":2468,"arg3":true}`+"\n", output.String())
+}
+
+// Test --dry-run is rejected with --output, nothing is printed
+func TestGoHandler_Execute_DryRunWithOutput(t *testing.T) {
+	var output bytes.Buffer
+	var validateCalled bool
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			validateCalled = true
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.outputWriter = &output
+	goHandler.cmdArgs.SetArgs([]string{"--dry-run", "--output", "json"})
+	goHandler.eventReader = getFileReader("test/event-entity-override.json")
+	err := goHandler.Execute()
+
+	assert.EqualError(t, err, "--dry-run cannot be used with --output, both print to stdout")
+	assert.False(t, validateCalled)
+	assert.Empty(t, output.String())
+}
+
+// Test explicit zero values are set and satisfy required options
+func TestGoHandler_IsSet(t *testing.T) {
+	tests := []struct {
+		env         string
+		args        []string
+		eventFile   string
+		expectedSet bool
+	}{
+		{"", []string{}, "test/event-no-override.json", false},
+		{"", []string{"--arg2", "0"}, "test/event-no-override.json", true},
+		{"0", []string{}, "test/event-no-override.json", true},
+		{"", []string{}, "test/event-check-override.json", true},
+	}
+
+	for _, test := range tests {
+		clearEnvironment()
+		if len(test.env) > 0 {
+			_ = os.Setenv("ENV_2", test.env)
+		}
+		options := getDefaultOptions()
+		values := handlerValues{}
+		options[0].Value = &values.arg1
+		options[1].Value = &values.arg2
+		options[1].Default = uint64(0)
+		options[1].Required = true
+		options[2].Value = &values.arg3
+
+		goHandler := NewGoHandler(&defaultHandlerConfig, options,
+			func(event *types.Event) error {
+				return nil
+			}, func(event *types.Event) error {
+				return nil
+			})
+		goHandler.cmdArgs.SetArgs(test.args)
+		goHandler.eventReader = getFileReader(test.eventFile)
+		err := goHandler.Execute()
+		_ = os.Unsetenv("ENV_2")
+
+		assert.Equal(t, test.expectedSet, goHandler.IsSet("arg2"))
+		assert.False(t, goHandler.IsSet("unknown"))
+		if test.expectedSet {
+			assert.Nil(t, err)
+		} else {
+			assert.EqualError(t, err, "required option arg2 was not set")
+		}
+	}
+}
+
+func TestGoHandler_ResolvedValues(t *testing.T) {
+	clearEnvironment()
+	_ = os.Setenv("ENV_2", "9999")
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+	var address net.IP
+	options = append(options, &HandlerConfigOption{
+		Argument: "address",
+		Default:  "127.0.0.1",
+		Usage:    "Address",
+		Parse:    ipParser(&address),
+	})
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{"--arg1", "cmdline-value"})
+	goHandler.eventReader = getFileReader("test/event-check-entity-override.json")
+	err := goHandler.Execute()
+	_ = os.Unsetenv("ENV_2")
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"arg1":    "value-check1",
+		"arg2":    uint64(1357),
+		"arg3":    false,
+		"address": "127.0.0.1",
+	}, goHandler.ResolvedValues())
+}
+
+// Test the default values are returned before Execute
+func TestGoHandler_ResolvedValues_Defaults(t *testing.T) {
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+
+	assert.Equal(t, map[string]interface{}{
+		"arg1": "Default1",
+		"arg2": uint64(33333),
+		"arg3": false,
+	}, goHandler.ResolvedValues())
+
+	goHandler.cmdArgs.SetArgs([]string{"--arg2", "1234"})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1234), goHandler.ResolvedValues()["arg2"])
+}
+
+// Test the defaults of raw options are returned before Execute
+func TestGoHandler_ResolvedValues_RawDefaults(t *testing.T) {
+	clearEnvironment()
+	var headers map[string]string
+	var ip net.IP
+	var rules routingRules
+	var count uint64
+	options := []*HandlerConfigOption{
+		{Argument: "m", Default: map[string]string{"X-Team": "ops"}, Value: &headers},
+		{Argument: "ip", Default: "10.0.0.1", Value: &ip},
+		{Argument: "rules", Default: routingRules{Default: "email"}, Value: &rules},
+		{Argument: "u", Default: uint64(5), Value: &count},
+	}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+
+	assert.Equal(t, map[string]interface{}{
+		"m":     map[string]string{"X-Team": "ops"},
+		"ip":    net.ParseIP("10.0.0.1"),
+		"rules": routingRules{Default: "email"},
+		"u":     uint64(5),
+	}, goHandler.ResolvedValues())
+
+	goHandler.cmdArgs.SetArgs([]string{"--m", "X-Team=dev"})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"X-Team": "dev"}, goHandler.ResolvedValues()["m"])
+	assert.Equal(t, net.ParseIP("10.0.0.1"), goHandler.ResolvedValues()["ip"])
+}
+
+func TestGoHandler_ResolvedValues_NilValue(t *testing.T) {
+	options := getDefaultOptions()
+	options[0].Value = nil
+	goHandler := NewGoHandler(&defaultHandlerConfig, options[:1],
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+
+	assert.Equal(t, map[string]interface{}{}, goHandler.ResolvedValues())
+}
+
+func TestGoHandler_VerifyAgainst(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			validateCalled = true
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			return nil
+		})
+	eventJSON, _ := ioutil.ReadFile("test/event-check-override.json")
+	err := goHandler.VerifyAgainst(eventJSON)
+
+	assert.Nil(t, err)
+	assert.True(t, validateCalled)
+	assert.False(t, executeCalled)
+	assert.Equal(t, "value-check1", values.arg1)
+	assert.Equal(t, uint64(1357), values.arg2)
+
+	// Executing after a verification must still work
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err = goHandler.Execute()
+	assert.Nil(t, err)
+	assert.True(t, executeCalled)
+}
+
+func TestGoHandler_VerifyAgainst_Error(t *testing.T) {
+	validateCalled := false
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			validateCalled = true
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+
+	eventJSON, _ := ioutil.ReadFile("test/event-invalid-json.json")
+	err := goHandler.VerifyAgainst(eventJSON)
+	assert.EqualError(t, err, "Failed to unmarshal STDIN data: invalid character ':' after object key:value pair")
+
+	eventJSON, _ = ioutil.ReadFile("test/event-check-override-invalid-value.json")
+	err = goHandler.VerifyAgainst(eventJSON)
+	assert.NotNil(t, err)
+	assert.False(t, validateCalled)
+}
+
+// Test validating an event without executing the handler
+func TestGoHandler_ValidateOnly(t *testing.T) {
+	var validateCalled, executeCalled bool
+	var port uint64
+	clearEnvironment()
+	portOption := HandlerConfigOption{
+		Argument: "port",
+		Default:  uint64(8080),
+		Path:     "path2",
+		Usage:    "Port",
+		Value:    &port,
+		Validate: portValidator,
+	}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, []*HandlerConfigOption{&portOption},
+		func(event *types.Event) error {
+			validateCalled = true
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-check-override.json")
+	err := goHandler.ValidateOnly()
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1357), port)
+	assert.True(t, validateCalled)
+	assert.False(t, executeCalled)
+}
+
+// Test ValidateOnly reports the errors Execute would
+func TestGoHandler_ValidateOnly_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		eventFile string
+		validate  func(event *types.Event) error
+	}{
+		{"invalid json", []string{}, "test/event-invalid-json.json", nil},
+		{"invalid check", []string{}, "test/event-invalid-check.json", nil},
+		{"option validator", []string{"--port", "70000"}, "test/event-no-override.json", nil},
+		{"validation function", []string{}, "test/event-no-override.json", func(event *types.Event) error {
+			return fmt.Errorf("validation error")
+		}},
+	}
+
+	for _, test := range tests {
+		errs := make([]error, 2)
+		for i := range errs {
+			var executeCalled bool
+			var port uint64
+			clearEnvironment()
+			portOption := HandlerConfigOption{
+				Argument: "port",
+				Default:  uint64(8080),
+				Usage:    "Port",
+				Value:    &port,
+				Validate: portValidator,
+			}
+			validate := test.validate
+			if validate == nil {
+				validate = func(event *types.Event) error {
+					return nil
+				}
+			}
+
+			goHandler := NewGoHandler(&defaultHandlerConfig, []*HandlerConfigOption{&portOption},
+				validate, func(event *types.Event) error {
+					executeCalled = true
+					return nil
+				}, WithErrorWriter(ioutil.Discard))
+			goHandler.cmdArgs.SetArgs(test.args)
+			goHandler.eventReader = getFileReader(test.eventFile)
+			if i == 0 {
+				errs[i] = goHandler.Execute()
+			} else {
+				errs[i] = goHandler.ValidateOnly()
+			}
+			assert.False(t, executeCalled, test.name)
+		}
+
+		assert.NotNil(t, errs[1], test.name)
+		assert.Equal(t, errs[0], errs[1], test.name)
+	}
+}
@@ -0,0 +1,124 @@
+package sensu
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// StatusError is an error carrying the status the handler exits with. Returned
+// by the validation or execute function, it makes Execute exit the process with
+// Status instead of returning, so handlers can report the OK, WARNING, CRITICAL
+// and UNKNOWN statuses of checks. Other errors are returned by Execute as
+// before, plugins exit with status 1 on them.
+type StatusError struct {
+	Status int
+	Err    error
+}
+
+func (statusErr *StatusError) Error() string {
+	if statusErr.Err == nil {
+		return ""
+	}
+	return statusErr.Err.Error()
+}
+
+func (statusErr *StatusError) Unwrap() error {
+	return statusErr.Err
+}
+
+// ValidationError is returned when the validation function fails, Err is the
+// error it returned.
+type ValidationError struct {
+	Err error
+}
+
+func (validationErr *ValidationError) Error() string {
+	return fmt.Sprintf("error validating input: %s", validationErr.Err)
+}
+
+func (validationErr *ValidationError) Unwrap() error {
+	return validationErr.Err
+}
+
+// ExecutionError is returned when the execute function fails, Err is the error
+// it returned.
+type ExecutionError struct {
+	Err error
+}
+
+func (executionErr *ExecutionError) Error() string {
+	return fmt.Sprintf("error executing handler: %s", executionErr.Err)
+}
+
+func (executionErr *ExecutionError) Unwrap() error {
+	return executionErr.Err
+}
+
+// MultiError combines the errors of the events of a batch, or of the execute
+// functions of NewGoHandlerMulti. It unwraps to each of them, so errors.As finds
+// a ValidationError, ExecutionError or StatusError returned for any of them.
+type MultiError struct {
+	Errors []error
+}
+
+func (multiErr *MultiError) Error() string {
+	messages := make([]string, len(multiErr.Errors))
+	for i, err := range multiErr.Errors {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (multiErr *MultiError) Unwrap() []error {
+	return multiErr.Errors
+}
+
+// Is reports whether any of the errors matches target. errors.Is only follows
+// Unwrap() []error from Go 1.20.
+func (multiErr *MultiError) Is(target error) bool {
+	for _, err := range multiErr.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors matching target. errors.As only follows
+// Unwrap() []error from Go 1.20.
+func (multiErr *MultiError) As(target interface{}) bool {
+	for _, err := range multiErr.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExitCode returns the exit code matching the error returned by Execute: 0
+// without error, the status of a StatusError, the configured
+// ValidationExitCode or ExecutionExitCode for validation and execution failures
+// and 1 for any other error.
+func (goHandler *GoHandler) ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Status
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) && goHandler.config.ValidationExitCode != 0 {
+		return goHandler.config.ValidationExitCode
+	}
+
+	var executionErr *ExecutionError
+	if errors.As(err, &executionErr) && goHandler.config.ExecutionExitCode != 0 {
+		return goHandler.config.ExecutionExitCode
+	}
+
+	return 1
+}
@@ -0,0 +1,165 @@
+package sensu
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGoHandler_Execute_StatusError(t *testing.T) {
+	tests := []struct {
+		validationErr  error
+		executeErr     error
+		expectedStatus int
+		expectedErr    string
+	}{
+		{nil, nil, -1, ""},
+		{nil, fmt.Errorf("execute error"), -1, "error executing handler: execute error"},
+		{nil, &StatusError{Status: CheckStateWarning, Err: fmt.Errorf("queue is filling up")}, CheckStateWarning,
+			"error executing handler: queue is filling up"},
+		{nil, &StatusError{Status: CheckStateCritical, Err: fmt.Errorf("queue is full")}, CheckStateCritical,
+			"error executing handler: queue is full"},
+		{&StatusError{Status: CheckStateUnknown, Err: fmt.Errorf("no queue")}, nil, CheckStateUnknown,
+			"error validating input: no queue"},
+	}
+
+	for _, test := range tests {
+		clearEnvironment()
+		options := getDefaultOptions()
+		values := handlerValues{}
+		options[0].Value = &values.arg1
+		options[1].Value = &values.arg2
+		options[2].Value = &values.arg3
+		validationErr := test.validationErr
+		executeErr := test.executeErr
+		goHandler := NewGoHandler(&defaultHandlerConfig, options,
+			func(event *types.Event) error {
+				return validationErr
+			}, func(event *types.Event) error {
+				return executeErr
+			})
+		exitStatus := -1
+		goHandler.exitFunction = func(status int) {
+			exitStatus = status
+		}
+		goHandler.cmdArgs.SetArgs([]string{})
+		goHandler.eventReader = getFileReader("test/event-no-override.json")
+		err := goHandler.Execute()
+
+		assert.Equal(t, test.expectedStatus, exitStatus)
+		if len(test.expectedErr) > 0 {
+			assert.EqualError(t, err, test.expectedErr)
+		} else {
+			assert.Nil(t, err)
+		}
+	}
+}
+
+func TestGoHandler_Execute_StatusErrorCleanup(t *testing.T) {
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+	goHandler := NewGoHandlerMulti(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, []func(event *types.Event) error{
+			func(event *types.Event) error {
+				return &StatusError{Status: CheckStateCritical, Err: fmt.Errorf("queue is full")}
+			},
+		}, true)
+	goHandler.SetCleanup(func() error {
+		return fmt.Errorf("cleanup error")
+	})
+	exitStatus := -1
+	goHandler.exitFunction = func(status int) {
+		exitStatus = status
+	}
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.Equal(t, CheckStateCritical, exitStatus)
+	assert.EqualError(t, err,
+		"error executing handler: function 1: queue is full (error cleaning up handler: cleanup error)")
+}
+
+func TestGoHandler_Execute_TypedErrors(t *testing.T) {
+	validationCause := fmt.Errorf("invalid input")
+	executionCause := fmt.Errorf("execute error")
+	clearEnvironment()
+
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-no-override.json", nil,
+		func(event *types.Event) error {
+			return validationCause
+		}, func(event *types.Event) error {
+			return nil
+		},
+		"Default1", uint64(33333), false)
+	assert.EqualError(t, err, "error validating input: invalid input")
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, validationCause, validationErr.Err)
+	var executionErr *ExecutionError
+	assert.False(t, errors.As(err, &executionErr))
+
+	err = goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-no-override.json", nil,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return executionCause
+		},
+		"Default1", uint64(33333), false)
+	assert.EqualError(t, err, "error executing handler: execute error")
+	assert.True(t, errors.As(err, &executionErr))
+	assert.Equal(t, executionCause, executionErr.Err)
+	assert.True(t, errors.Is(err, executionCause))
+	assert.False(t, errors.As(err, &validationErr))
+}
+
+func TestGoHandler_ExitCode(t *testing.T) {
+	handlerConfig := defaultHandlerConfig
+	goHandler := NewGoHandler(&handlerConfig, getDefaultOptions(), nil, nil)
+	validationErr := &ValidationError{Err: fmt.Errorf("invalid input")}
+	executionErr := &ExecutionError{Err: fmt.Errorf("execute error")}
+	statusErr := &ValidationError{Err: &StatusError{Status: CheckStateWarning, Err: fmt.Errorf("warning")}}
+
+	assert.Equal(t, 0, goHandler.ExitCode(nil))
+	assert.Equal(t, 1, goHandler.ExitCode(fmt.Errorf("failed to read event")))
+	assert.Equal(t, 1, goHandler.ExitCode(validationErr))
+	assert.Equal(t, 1, goHandler.ExitCode(executionErr))
+	assert.Equal(t, CheckStateWarning, goHandler.ExitCode(statusErr))
+
+	handlerConfig.ValidationExitCode = 3
+	handlerConfig.ExecutionExitCode = 2
+	assert.Equal(t, 0, goHandler.ExitCode(nil))
+	assert.Equal(t, 1, goHandler.ExitCode(fmt.Errorf("failed to read event")))
+	assert.Equal(t, 3, goHandler.ExitCode(validationErr))
+	assert.Equal(t, 2, goHandler.ExitCode(executionErr))
+	assert.Equal(t, 2, goHandler.ExitCode(fmt.Errorf("%w (error cleaning up handler: failed)", executionErr)))
+	assert.Equal(t, CheckStateWarning, goHandler.ExitCode(statusErr))
+}
+
+// Test the errors of a MultiError are matched without relying on errors.Is and
+// errors.As following Unwrap() []error, which older Go releases do not
+func TestMultiError_IsAs(t *testing.T) {
+	sentinel := errors.New("sentinel")
+	multiErr := &MultiError{Errors: []error{
+		fmt.Errorf("event 1: %w", sentinel),
+		fmt.Errorf("event 2: %w", &ValidationError{Err: fmt.Errorf("invalid input")}),
+	}}
+
+	assert.True(t, multiErr.Is(sentinel))
+	assert.False(t, multiErr.Is(errors.New("other")))
+
+	var validationErr *ValidationError
+	assert.True(t, multiErr.As(&validationErr))
+	assert.EqualError(t, validationErr, "error validating input: invalid input")
+	var executionErr *ExecutionError
+	assert.False(t, multiErr.As(&executionErr))
+	assert.Equal(t, SummaryStatusValidationError, summaryStatus(multiErr))
+}
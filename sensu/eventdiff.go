@@ -0,0 +1,93 @@
+package sensu
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Types of changes reported in a DiffEntry
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// DiffEntry describes a single field that differs between two events. Path is
+// the dot separated location of the field in the event's JSON representation.
+type DiffEntry struct {
+	Path string      `json:"path"`
+	Type string      `json:"type"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// EventDiff compares two JSON encoded events field by field and returns the
+// added, removed and changed fields sorted by path.
+func EventDiff(before []byte, after []byte) ([]DiffEntry, error) {
+	var beforeValue, afterValue interface{}
+	if err := json.Unmarshal(before, &beforeValue); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(after, &afterValue); err != nil {
+		return nil, err
+	}
+
+	diff := []DiffEntry{}
+	diff = diffValues(diff, "", beforeValue, afterValue)
+	sort.Slice(diff, func(i, j int) bool {
+		return diff[i].Path < diff[j].Path
+	})
+	return diff, nil
+}
+
+func diffValues(diff []DiffEntry, path string, before interface{}, after interface{}) []DiffEntry {
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if beforeIsMap && afterIsMap {
+		for key, beforeValue := range beforeMap {
+			afterValue, ok := afterMap[key]
+			if !ok {
+				diff = append(diff, DiffEntry{Path: joinDiffPath(path, key), Type: DiffRemoved, Old: beforeValue})
+				continue
+			}
+			diff = diffValues(diff, joinDiffPath(path, key), beforeValue, afterValue)
+		}
+		for key, afterValue := range afterMap {
+			if _, ok := beforeMap[key]; !ok {
+				diff = append(diff, DiffEntry{Path: joinDiffPath(path, key), Type: DiffAdded, New: afterValue})
+			}
+		}
+		return diff
+	}
+
+	beforeSlice, beforeIsSlice := before.([]interface{})
+	afterSlice, afterIsSlice := after.([]interface{})
+	if beforeIsSlice && afterIsSlice {
+		for i := 0; i < len(beforeSlice) || i < len(afterSlice); i++ {
+			elementPath := joinDiffPath(path, strconv.Itoa(i))
+			switch {
+			case i >= len(afterSlice):
+				diff = append(diff, DiffEntry{Path: elementPath, Type: DiffRemoved, Old: beforeSlice[i]})
+			case i >= len(beforeSlice):
+				diff = append(diff, DiffEntry{Path: elementPath, Type: DiffAdded, New: afterSlice[i]})
+			default:
+				diff = diffValues(diff, elementPath, beforeSlice[i], afterSlice[i])
+			}
+		}
+		return diff
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		diff = append(diff, DiffEntry{Path: path, Type: DiffChanged, Old: before, New: after})
+	}
+	return diff
+}
+
+func joinDiffPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,78 @@
+package sensu
+
+import (
+	"encoding/json"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func diffTestEvent() *types.Event {
+	return &types.Event{
+		Timestamp: 1550816106,
+		Check: &types.Check{
+			ObjectMeta: types.ObjectMeta{
+				Name: "CheckName",
+				Annotations: map[string]string{
+					"team": "ops",
+				},
+			},
+		},
+		Metrics: &types.Metrics{
+			Points: []*types.MetricPoint{
+				{Name: "cpu", Value: 0.5, Timestamp: 1550816106},
+			},
+		},
+	}
+}
+
+func TestEventDiff(t *testing.T) {
+	event := diffTestEvent()
+	before, _ := json.Marshal(event)
+
+	event.Check.Annotations["team"] = "dev"
+	event.Metrics.Points = append(event.Metrics.Points, &types.MetricPoint{Name: "mem", Value: 12, Timestamp: 1550816106})
+	after, _ := json.Marshal(event)
+
+	diff, err := EventDiff(before, after)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []DiffEntry{
+		{Path: "check.metadata.annotations.team", Type: DiffChanged, Old: "ops", New: "dev"},
+		{Path: "metrics.points.1", Type: DiffAdded, New: map[string]interface{}{
+			"name": "mem", "value": float64(12), "timestamp": float64(1550816106), "tags": nil,
+		}},
+	}, diff)
+}
+
+func TestEventDiff_Removed(t *testing.T) {
+	event := diffTestEvent()
+	before, _ := json.Marshal(event)
+
+	event.Metrics = nil
+	after, _ := json.Marshal(event)
+
+	diff, err := EventDiff(before, after)
+
+	assert.Nil(t, err)
+	assert.Len(t, diff, 1)
+	assert.Equal(t, "metrics", diff[0].Path)
+	assert.Equal(t, DiffRemoved, diff[0].Type)
+}
+
+func TestEventDiff_NoChange(t *testing.T) {
+	before, _ := json.Marshal(diffTestEvent())
+
+	diff, err := EventDiff(before, before)
+
+	assert.Nil(t, err)
+	assert.Empty(t, diff)
+}
+
+func TestEventDiff_InvalidJSON(t *testing.T) {
+	before, _ := json.Marshal(diffTestEvent())
+
+	_, err := EventDiff(before, []byte("not json"))
+
+	assert.NotNil(t, err)
+}
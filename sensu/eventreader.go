@@ -0,0 +1,216 @@
+package sensu
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ghodss/yaml"
+	"github.com/sensu/sensu-go/types"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// readSensuEventData reads the event data from the event file or the event
+// reader
+func (goHandler *GoHandler) readSensuEventData() ([]byte, error) {
+	reader, closeReader, err := eventFileReader(goHandler.eventFile, goHandler.eventReader)
+	if err != nil {
+		return nil, err
+	}
+	defer closeReader()
+
+	eventData, err := readEventData(reader, goHandler.inputFormat)
+	if err != nil {
+		return nil, withEventSource(err, goHandler.eventFile)
+	}
+
+	return eventData, nil
+}
+
+// EventReadError is returned when the event could not be read, as opposed to
+// being read but failing to unmarshal or validate. Source names where the event
+// was read from, stdin or the event file, when known.
+type EventReadError struct {
+	Source string
+	Err    error
+}
+
+func (e *EventReadError) Error() string {
+	if len(e.Source) == 0 {
+		return fmt.Sprintf("failed to read event: %s", e.Err)
+	}
+	return fmt.Sprintf("failed to read event from %s: %s", e.Source, e.Err)
+}
+
+// withEventSource sets the source of err to stdin or eventFile if it is an
+// EventReadError
+func withEventSource(err error, eventFile string) error {
+	if readErr, ok := err.(*EventReadError); ok {
+		readErr.Source = "stdin"
+		if len(eventFile) > 0 {
+			readErr.Source = eventFile
+		}
+	}
+	return err
+}
+
+// eventFileReader opens eventFile if set, or returns the default reader
+// otherwise. The returned function closes the file.
+func eventFileReader(eventFile string, defaultReader io.Reader) (io.Reader, func(), error) {
+	if len(eventFile) == 0 {
+		return defaultReader, func() {}, nil
+	}
+
+	file, err := os.Open(eventFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open event file: %s", err)
+	}
+	return file, func() {
+		_ = file.Close()
+	}, nil
+}
+
+// Formats of the event read from stdin or the event file
+const (
+	InputFormatJSON = "json"
+	InputFormatYAML = "yaml"
+)
+
+// readEvent reads and validates the Sensu event available in reader, encoded
+// in the given input format
+func readEvent(reader io.Reader, config *HandlerConfig, inputFormat string) (*types.Event, error) {
+	eventData, err := readEventData(reader, inputFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseEvent(eventData, config, inputFormat)
+}
+
+// readEventData reads the event data, uncompressing it if needed
+func readEventData(reader io.Reader, inputFormat string) ([]byte, error) {
+	if inputFormat != InputFormatJSON && inputFormat != InputFormatYAML {
+		return nil, fmt.Errorf("invalid input format %q, must be one of [%s %s]",
+			inputFormat, InputFormatJSON, InputFormatYAML)
+	}
+
+	eventData, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, &EventReadError{Err: err}
+	}
+
+	eventData, err = decompressEvent(eventData)
+	if err != nil {
+		return nil, &EventReadError{Err: err}
+	}
+
+	return eventData, nil
+}
+
+// parseEvent unmarshals the event data and validates the event
+func parseEvent(eventData []byte, config *HandlerConfig, inputFormat string) (*types.Event, error) {
+	var err error
+	sensuEvent := &types.Event{}
+	if inputFormat == InputFormatYAML {
+		err = yaml.Unmarshal(eventData, sensuEvent)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal STDIN data (yaml): %s", err)
+		}
+	} else {
+		err = json.Unmarshal(eventData, sensuEvent)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal STDIN data: %s", err)
+		}
+	}
+
+	if err = checkEvent(sensuEvent, config); err != nil {
+		return nil, err
+	}
+
+	return sensuEvent, nil
+}
+
+// checkEvent validates the event and its age
+func checkEvent(sensuEvent *types.Event, config *HandlerConfig) error {
+	if err := validateEvent(sensuEvent, config); err != nil {
+		return err
+	}
+
+	return validateEventAge(sensuEvent, config.MaxEventIntervals, time.Now())
+}
+
+// decompressEvent returns the uncompressed event data when it starts with the
+// gzip magic bytes, and the data unchanged otherwise
+func decompressEvent(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return data, nil
+	}
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	return ioutil.ReadAll(gzipReader)
+}
+
+func validateEvent(event *types.Event, config *HandlerConfig) error {
+	if !config.SkipTimestampValidation && event.Timestamp <= 0 {
+		return errors.New("timestamp is missing or must be greater than zero")
+	}
+
+	if !config.SkipEntityValidation {
+		if event.Entity == nil {
+			return errors.New("entity is missing from event")
+		}
+		if err := event.Entity.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if !config.SkipCheckValidation {
+		if !event.HasCheck() {
+			return errors.New("check is missing from event")
+		}
+		if err := event.Check.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateEventAge rejects events whose timestamp is older than maxIntervals
+// times the check interval. Checks without an interval are not verified.
+func validateEventAge(event *types.Event, maxIntervals uint32, now time.Time) error {
+	if maxIntervals == 0 || !event.HasCheck() || event.Check.Interval == 0 || event.Timestamp <= 0 {
+		return nil
+	}
+
+	maxAge := time.Duration(maxIntervals) * time.Duration(event.Check.Interval) * time.Second
+	age := now.Sub(time.Unix(event.Timestamp, 0))
+	if age > maxAge {
+		return fmt.Errorf("event is %s old, older than %d check intervals of %ds", age, maxIntervals,
+			event.Check.Interval)
+	}
+
+	return nil
+}
+
+// ReadEvent reads the event from the event file or the event reader and checks
+// it is valid. It is the first stage of Execute, followed by ResolveOptions and
+// Validate, which can be called on their own to run a handler without
+// executing it.
+func (goHandler *GoHandler) ReadEvent() (*types.Event, error) {
+	eventData, err := goHandler.readSensuEventData()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseEvent(eventData, goHandler.config, goHandler.inputFormat)
+}
@@ -0,0 +1,552 @@
+package sensu
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// Test invalid event - no timestamp
+func TestGoHandler_Execute_EventNoTimestamp(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-no-timestamp.json", defaultCmdLineArgs,
+		func(event *types.Event) error {
+			validateCalled = true
+			assert.NotNil(t, event)
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			assert.NotNil(t, event)
+			return nil
+		},
+		"value-arg1", uint64(7531), false)
+	assert.NotNil(t, err)
+	assert.EqualError(t, err, "timestamp is missing or must be greater than zero")
+	assert.False(t, validateCalled)
+	assert.False(t, executeCalled)
+}
+
+// Test invalid event - timestamp 0
+func TestGoHandler_Execute_EventTimestampZero(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-timestamp-zero.json", defaultCmdLineArgs,
+		func(event *types.Event) error {
+			validateCalled = true
+			assert.NotNil(t, event)
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			assert.NotNil(t, event)
+			return nil
+		},
+		"value-arg1", uint64(7531), false)
+	assert.NotNil(t, err)
+	assert.EqualError(t, err, "timestamp is missing or must be greater than zero")
+	assert.False(t, validateCalled)
+	assert.False(t, executeCalled)
+}
+
+// Test invalid event - no entity
+func TestGoHandler_Execute_EventNoEntity(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-no-entity.json", defaultCmdLineArgs,
+		func(event *types.Event) error {
+			validateCalled = true
+			assert.NotNil(t, event)
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			assert.NotNil(t, event)
+			return nil
+		},
+		"value-arg1", uint64(7531), false)
+	assert.NotNil(t, err)
+	assert.EqualError(t, err, "entity is missing from event")
+	assert.False(t, validateCalled)
+	assert.False(t, executeCalled)
+}
+
+// Test invalid event - invalid entity
+func TestGoHandler_Execute_EventInvalidEntity(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-invalid-entity.json", defaultCmdLineArgs,
+		func(event *types.Event) error {
+			validateCalled = true
+			assert.NotNil(t, event)
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			assert.NotNil(t, event)
+			return nil
+		},
+		"value-arg1", uint64(7531), false)
+	assert.NotNil(t, err)
+	assert.EqualError(t, err, "entity name must not be empty")
+	assert.False(t, validateCalled)
+	assert.False(t, executeCalled)
+}
+
+// Test invalid event - no check
+func TestGoHandler_Execute_EventNoCheck(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-no-check.json", defaultCmdLineArgs,
+		func(event *types.Event) error {
+			validateCalled = true
+			assert.NotNil(t, event)
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			assert.NotNil(t, event)
+			return nil
+		},
+		"value-arg1", uint64(7531), false)
+	assert.NotNil(t, err)
+	assert.EqualError(t, err, "check is missing from event")
+	assert.False(t, validateCalled)
+	assert.False(t, executeCalled)
+}
+
+// Test event without check when the check validation is skipped
+func TestGoHandler_Execute_EventNoCheckSkipValidation(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	handlerConfig := defaultHandlerConfig
+	handlerConfig.SkipCheckValidation = true
+	handlerConfig.MaxEventIntervals = 3
+	err := goHandlerExecuteUtil(t, &handlerConfig, "test/event-no-check-entity-override.json", []string{},
+		func(event *types.Event) error {
+			validateCalled = true
+			assert.Nil(t, event.Check)
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			return nil
+		},
+		"value-entity1", uint64(2468), true)
+	assert.Nil(t, err)
+	assert.True(t, validateCalled)
+	assert.True(t, executeCalled)
+}
+
+// Test skipping the entity and timestamp validation
+func TestValidateEvent_Skip(t *testing.T) {
+	event := &types.Event{}
+	assert.EqualError(t, validateEvent(event, &defaultHandlerConfig), "timestamp is missing or must be greater than zero")
+
+	handlerConfig := defaultHandlerConfig
+	handlerConfig.SkipTimestampValidation = true
+	assert.EqualError(t, validateEvent(event, &handlerConfig), "entity is missing from event")
+
+	handlerConfig.SkipEntityValidation = true
+	assert.EqualError(t, validateEvent(event, &handlerConfig), "check is missing from event")
+
+	handlerConfig.SkipCheckValidation = true
+	assert.Nil(t, validateEvent(event, &handlerConfig))
+}
+
+// Test invalid event - invalid check
+func TestGoHandler_Execute_EventInvalidCheck(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-invalid-check.json", defaultCmdLineArgs,
+		func(event *types.Event) error {
+			validateCalled = true
+			assert.NotNil(t, event)
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			assert.NotNil(t, event)
+			return nil
+		},
+		"value-arg1", uint64(7531), false)
+	assert.NotNil(t, err)
+	assert.EqualError(t, err, "check name must not be empty")
+	assert.False(t, validateCalled)
+	assert.False(t, executeCalled)
+}
+
+// Test stale event - older than the allowed number of check intervals
+func TestGoHandler_Execute_EventTooOld(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	handlerConfig := defaultHandlerConfig
+	handlerConfig.MaxEventIntervals = 3
+	err := goHandlerExecuteUtil(t, &handlerConfig, "test/event-no-override.json", defaultCmdLineArgs,
+		func(event *types.Event) error {
+			validateCalled = true
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			return nil
+		},
+		"value-arg1", uint64(7531), false)
+	assert.NotNil(t, err)
+	assert.False(t, validateCalled)
+	assert.False(t, executeCalled)
+}
+
+func TestValidateEventAge(t *testing.T) {
+	now := time.Unix(1550816106, 0)
+	event := &types.Event{
+		Check: &types.Check{
+			Interval: 20,
+		},
+	}
+
+	event.Timestamp = now.Add(-50 * time.Second).Unix()
+	assert.Nil(t, validateEventAge(event, 3, now))
+
+	event.Timestamp = now.Add(-61 * time.Second).Unix()
+	assert.EqualError(t, validateEventAge(event, 3, now), "event is 1m1s old, older than 3 check intervals of 20s")
+
+	assert.Nil(t, validateEventAge(event, 0, now))
+
+	event.Check.Interval = 0
+	assert.Nil(t, validateEventAge(event, 3, now))
+}
+
+// Test unmarshalling error
+func TestGoHandler_Execute_EventInvalidJson(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-invalid-json.json", defaultCmdLineArgs,
+		func(event *types.Event) error {
+			validateCalled = true
+			assert.NotNil(t, event)
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			assert.NotNil(t, event)
+			return nil
+		},
+		"value-arg1", uint64(7531), false)
+	assert.NotNil(t, err)
+	assert.EqualError(t, err, "Failed to unmarshal STDIN data: invalid character ':' after object key:value pair")
+	assert.False(t, validateCalled)
+	assert.False(t, executeCalled)
+}
+
+// Test fail to unmarshal stdin
+func TestGoHandler_Execute_UnmarshalError(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-invalid-json.json", defaultCmdLineArgs,
+		func(event *types.Event) error {
+			validateCalled = true
+			assert.NotNil(t, event)
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			assert.NotNil(t, event)
+			return nil
+		},
+		"value-arg1", uint64(7531), false)
+	assert.NotNil(t, err)
+	assert.EqualError(t, err, "Failed to unmarshal STDIN data: invalid character ':' after object key:value pair")
+	assert.False(t, validateCalled)
+	assert.False(t, executeCalled)
+}
+
+type failingReader struct {
+	data []byte
+	err  error
+}
+
+// Read returns the data once, then fails
+func (reader *failingReader) Read(p []byte) (int, error) {
+	if len(reader.data) == 0 {
+		return 0, reader.err
+	}
+	n := copy(p, reader.data)
+	reader.data = reader.data[n:]
+	return n, nil
+}
+
+// Test reader failing after partial data
+func TestGoHandler_Execute_PartialReadError(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	goHandler := NewGoHandler(&defaultHandlerConfig, []*HandlerConfigOption{},
+		func(event *types.Event) error {
+			validateCalled = true
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = &failingReader{data: []byte(`{"timestamp": 15508`), err: fmt.Errorf("connection reset")}
+	err := goHandler.Execute()
+
+	assert.EqualError(t, err, "failed to read event from stdin: connection reset")
+	readErr, ok := err.(*EventReadError)
+	assert.True(t, ok)
+	assert.Equal(t, "stdin", readErr.Source)
+	assert.False(t, validateCalled)
+	assert.False(t, executeCalled)
+}
+
+// Test the event file is named when it cannot be read
+func TestGoHandler_Execute_EventFileReadError(t *testing.T) {
+	clearEnvironment()
+	goHandler := NewGoHandler(&defaultHandlerConfig, []*HandlerConfigOption{},
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{"--event-file", "test"})
+	err := goHandler.Execute()
+
+	assert.EqualError(t, err, "failed to read event from test: read test: is a directory")
+}
+
+// Test a gzip compressed event is parsed like the plain event
+func TestReadEvent_Gzip(t *testing.T) {
+	eventJSON, err := ioutil.ReadFile("test/event-check-entity-override.json")
+	assert.Nil(t, err)
+
+	var compressed bytes.Buffer
+	gzipWriter := gzip.NewWriter(&compressed)
+	_, err = gzipWriter.Write(eventJSON)
+	assert.Nil(t, err)
+	assert.Nil(t, gzipWriter.Close())
+
+	plainEvent, err := readEvent(bytes.NewReader(eventJSON), &defaultHandlerConfig, InputFormatJSON)
+	assert.Nil(t, err)
+	gzipEvent, err := readEvent(&compressed, &defaultHandlerConfig, InputFormatJSON)
+	assert.Nil(t, err)
+	assert.Equal(t, plainEvent, gzipEvent)
+	assert.Equal(t, "webserver01", gzipEvent.Entity.Name)
+}
+
+// Test truncated gzip data
+func TestReadEvent_GzipTruncated(t *testing.T) {
+	var compressed bytes.Buffer
+	gzipWriter := gzip.NewWriter(&compressed)
+	_, _ = gzipWriter.Write([]byte(`{"timestamp": 1550816106}`))
+	_ = gzipWriter.Close()
+
+	_, err := readEvent(bytes.NewReader(compressed.Bytes()[:12]), &defaultHandlerConfig, InputFormatJSON)
+	assert.NotNil(t, err)
+	_, ok := err.(*EventReadError)
+	assert.True(t, ok)
+}
+
+// Test a YAML event is parsed like the JSON event
+func TestReadEvent_YAML(t *testing.T) {
+	jsonEvent, err := readEvent(getFileReader("test/event-check-entity-override.json"),
+		&defaultHandlerConfig, InputFormatJSON)
+	assert.Nil(t, err)
+	yamlEvent, err := readEvent(getFileReader("test/event-check-entity-override.yaml"),
+		&defaultHandlerConfig, InputFormatYAML)
+	assert.Nil(t, err)
+	assert.Equal(t, jsonEvent, yamlEvent)
+
+	_, err = readEvent(bytes.NewReader([]byte("timestamp: [")), &defaultHandlerConfig, InputFormatYAML)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Failed to unmarshal STDIN data (yaml): ")
+
+	_, err = readEvent(getFileReader("test/event-check-entity-override.yaml"), &defaultHandlerConfig, "xml")
+	assert.EqualError(t, err, `invalid input format "xml", must be one of [json yaml]`)
+}
+
+// Test a YAML event goes through the same validation and overrides
+func TestGoHandler_Execute_InputFormatYAML(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-check-entity-override.yaml",
+		[]string{"--input-format", "yaml"},
+		func(event *types.Event) error {
+			validateCalled = true
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			return nil
+		},
+		"value-check1", uint64(1357), false)
+	assert.Nil(t, err)
+	assert.True(t, validateCalled)
+	assert.True(t, executeCalled)
+
+	// JSON remains the default
+	clearEnvironment()
+	err = goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-check-entity-override.yaml", []string{},
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		},
+		"Default1", uint64(33333), false)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Failed to unmarshal STDIN data: ")
+}
+
+// Test reading the event from a file
+func TestGoHandler_Execute_EventFile(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-invalid-json.json",
+		[]string{"--event-file", "test/event-check-override.json"},
+		func(event *types.Event) error {
+			validateCalled = true
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			return nil
+		},
+		"value-check1", uint64(1357), false)
+	assert.Nil(t, err)
+	assert.True(t, validateCalled)
+	assert.True(t, executeCalled)
+}
+
+// Test the event file is parsed the same way as stdin
+func TestGoHandler_Execute_EventFileMatchesStdin(t *testing.T) {
+	var stdinEvent, fileEvent *types.Event
+	clearEnvironment()
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-entity-override.json", []string{},
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			stdinEvent = event
+			return nil
+		},
+		"value-entity1", uint64(2468), true)
+	assert.Nil(t, err)
+
+	err = goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-no-override.json",
+		[]string{"--event-file", "test/event-entity-override.json"},
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			fileEvent = event
+			return nil
+		},
+		"value-entity1", uint64(2468), true)
+	assert.Nil(t, err)
+	assert.Equal(t, stdinEvent, fileEvent)
+}
+
+// Test missing event file
+func TestGoHandler_Execute_EventFileMissing(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-no-override.json",
+		[]string{"--event-file", "test/does-not-exist.json"},
+		func(event *types.Event) error {
+			validateCalled = true
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			return nil
+		},
+		"Default1", uint64(33333), false)
+	assert.EqualError(t, err, "failed to open event file: open test/does-not-exist.json: no such file or directory")
+	assert.False(t, validateCalled)
+	assert.False(t, executeCalled)
+}
+
+func TestGoHandler_ReadEventResolveOptions(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			validateCalled = true
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			return nil
+		}, WithEventReader(getFileReader("test/event-check-override.json")))
+
+	event, err := goHandler.ReadEvent()
+	assert.Nil(t, err)
+	assert.NotNil(t, event)
+	assert.Equal(t, "check-nginx", event.Check.Name)
+
+	err = goHandler.ResolveOptions(event)
+	assert.Nil(t, err)
+	assert.Equal(t, "value-check1", values.arg1)
+	assert.Equal(t, uint64(1357), values.arg2)
+	assert.False(t, validateCalled)
+
+	err = goHandler.Validate(event)
+	assert.Nil(t, err)
+	assert.True(t, validateCalled)
+	assert.False(t, executeCalled)
+}
+
+// Test the stages called on their own read the config file and the keyspace
+// given as handler options or in the environment
+func TestGoHandler_ReadEventResolveOptions_ConfigFileKeyspace(t *testing.T) {
+	tests := []struct {
+		name           string
+		env            string
+		eventFile      string
+		handlerOptions []GoHandlerOption
+		expected       handlerValues
+	}{
+		{"config file", "", "test/event-no-override.json",
+			[]GoHandlerOption{WithConfigFile("test/config.yaml")}, handlerValues{"value-config1", 2468, true}},
+		{"keyspace", "", "test/event-split-keyspace-override.json",
+			[]GoHandlerOption{WithKeyspace("example.com/org/config")}, handlerValues{"Default1", 4321, true}},
+		{"keyspace environment", "example.com/org/config", "test/event-split-keyspace-override.json",
+			nil, handlerValues{"Default1", 4321, true}},
+	}
+
+	for _, test := range tests {
+		clearEnvironment()
+		_ = os.Setenv(KeyspaceEnv, test.env)
+		options := getDefaultOptions()
+		values := handlerValues{}
+		options[0].Value = &values.arg1
+		options[1].Value = &values.arg2
+		options[2].Value = &values.arg3
+
+		goHandler := NewGoHandler(&defaultHandlerConfig, options,
+			func(event *types.Event) error {
+				return nil
+			}, func(event *types.Event) error {
+				return nil
+			}, append(test.handlerOptions, WithEventReader(getFileReader(test.eventFile)))...)
+
+		event, err := goHandler.ReadEvent()
+		assert.Nil(t, err, test.name)
+		err = goHandler.ResolveOptions(event)
+		_ = os.Unsetenv(KeyspaceEnv)
+
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.expected, values, test.name)
+		assert.Equal(t, "sensu.io/plugins/segp/config", defaultHandlerConfig.Keyspace, test.name)
+	}
+}
+
+func TestGoHandler_ReadEvent_Error(t *testing.T) {
+	goHandler := NewGoHandler(&defaultHandlerConfig, getDefaultOptions(),
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		}, WithEventReader(getFileReader("test/event-invalid-json.json")))
+
+	event, err := goHandler.ReadEvent()
+	assert.Nil(t, event)
+	assert.EqualError(t, err, "Failed to unmarshal STDIN data: invalid character ':' after object key:value pair")
+}
@@ -0,0 +1,129 @@
+package sensu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sensu/sensu-enterprise-go-plugin/args"
+	"github.com/sensu/sensu-go/types"
+	"io"
+	"os"
+)
+
+// Check statuses understood by Sensu
+const (
+	CheckStateOK       = 0
+	CheckStateWarning  = 1
+	CheckStateCritical = 2
+	CheckStateUnknown  = 3
+)
+
+// CheckConfig contains the check information
+type CheckConfig struct {
+	Name    string
+	Short   string
+	Timeout uint64
+}
+
+// GoCheck runs a Sensu check. Unlike GoHandler it does not read an event, its
+// options are resolved from the command line, the environment, the --config
+// file, the sources added with ResolveFrom and defaults.
+type GoCheck struct {
+	optionResolver
+	config             *CheckConfig
+	validationFunction func() error
+	checkFunction      func() (int, string, error)
+	outputWriter       io.Writer
+	exitFunction       func(int)
+}
+
+// NewGoCheck creates a GoCheck with the given configuration, options and check
+// function. The check function returns the check status and output.
+func NewGoCheck(config *CheckConfig, options []*HandlerConfigOption, checkFunction func() (int, string, error)) *GoCheck {
+	handlerConfig := &HandlerConfig{Name: config.Name, Short: config.Short, Timeout: config.Timeout}
+	goCheck := &GoCheck{
+		optionResolver: newOptionResolver(handlerConfig, options, os.Stderr),
+		config:         config,
+		checkFunction:  checkFunction,
+		outputWriter:   os.Stdout,
+		exitFunction:   os.Exit,
+	}
+	goCheck.registerFlags(args.NewArgs(config.Name, config.Short, goCheck.cobraExecute), false)
+
+	return goCheck
+}
+
+// NewGoCheckWithValidation creates a GoCheck from a validation function and an
+// execute function, mirroring GoHandler. The check is OK when executeFunction
+// returns nil and CRITICAL, with the error as output, when it fails.
+func NewGoCheckWithValidation(config *CheckConfig, options []*HandlerConfigOption,
+	validationFunction func() error, executeFunction func() error) *GoCheck {
+	goCheck := NewGoCheck(config, options, func() (int, string, error) {
+		if err := executeFunction(); err != nil {
+			return CheckStateCritical, fmt.Sprintf("error executing check: %s", err), nil
+		}
+		return CheckStateOK, "", nil
+	})
+	goCheck.validationFunction = validationFunction
+
+	return goCheck
+}
+
+// Execute parses the command line arguments, runs the check, prints its output
+// and exits with its status. An error is only returned if the arguments could
+// not be parsed.
+func (goCheck *GoCheck) Execute() error {
+	// Setup arguments
+	err := goCheck.registerOptions()
+	if err != nil {
+		return err
+	}
+
+	// This will call cobraExecute so put the rest of the logic in there
+	return goCheck.cmdArgs.Execute()
+}
+
+// SetOutputWriter sets the writer receiving the check output, stdout by
+// default.
+func (goCheck *GoCheck) SetOutputWriter(writer io.Writer) {
+	goCheck.outputWriter = writer
+}
+
+func (goCheck *GoCheck) cobraExecute(_ []string) error {
+	// Resolve the options and validate the input
+	var validationFunction func(ctx context.Context, event *types.Event) error
+	if goCheck.validationFunction != nil {
+		validationFunction = func(_ context.Context, _ *types.Event) error {
+			return goCheck.validationFunction()
+		}
+	}
+	err := goCheck.resolve(nil, validationFunction)
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		_, _ = fmt.Fprintln(goCheck.outputWriter, validationErr.Error())
+		goCheck.exitFunction(CheckStateUnknown)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var status int
+	var output string
+	err = recoverHandler(context.Background(), nil, func(_ context.Context, _ *types.Event) error {
+		var checkErr error
+		status, output, checkErr = goCheck.checkFunction()
+		return checkErr
+	})
+	if err != nil {
+		status = CheckStateUnknown
+		output = fmt.Sprintf("error executing check: %s", err)
+	}
+
+	if len(output) > 0 {
+		_, _ = fmt.Fprintln(goCheck.outputWriter, output)
+	}
+	goCheck.exitFunction(status)
+
+	return nil
+}
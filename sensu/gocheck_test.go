@@ -0,0 +1,208 @@
+package sensu
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+var defaultCheckConfig = CheckConfig{
+	Name:    "TestCheck",
+	Short:   "Short Description",
+	Timeout: 10,
+}
+
+func TestNewGoCheck(t *testing.T) {
+	options := getDefaultOptions()
+	goCheck := NewGoCheck(&defaultCheckConfig, options, func() (int, string, error) {
+		return CheckStateOK, "", nil
+	})
+
+	assert.NotNil(t, goCheck)
+	assert.Equal(t, options, goCheck.options)
+	assert.Equal(t, &defaultCheckConfig, goCheck.config)
+	assert.NotNil(t, goCheck.checkFunction)
+	assert.Equal(t, os.Stdout, goCheck.outputWriter)
+	assert.NotNil(t, goCheck.exitFunction)
+	assert.NotNil(t, goCheck.cmdArgs)
+}
+
+func goCheckExecuteUtil(t *testing.T, cmdLineArgs []string, checkFunction func() (int, string, error),
+	expectedValue1 interface{}, expectedValue2 interface{}, expectedValue3 interface{}) (int, string, error) {
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goCheck := NewGoCheck(&defaultCheckConfig, options, checkFunction)
+	goCheck.cmdArgs.SetArgs(cmdLineArgs)
+
+	// Capture stdout and the exit status
+	var output bytes.Buffer
+	exitStatus := -1
+	goCheck.outputWriter = &output
+	goCheck.exitFunction = func(status int) {
+		exitStatus = status
+	}
+	err := goCheck.Execute()
+
+	assert.Equal(t, expectedValue1, values.arg1)
+	assert.Equal(t, expectedValue2, values.arg2)
+	assert.Equal(t, expectedValue3, values.arg3)
+
+	return exitStatus, output.String(), err
+}
+
+// Test check status and output
+func TestGoCheck_Execute(t *testing.T) {
+	clearEnvironment()
+	_ = os.Setenv("ENV_2", "9753")
+	status, output, err := goCheckExecuteUtil(t, []string{"--arg1", "value-arg1"}, func() (int, string, error) {
+		return CheckStateWarning, "CheckOutput", nil
+	}, "value-arg1", uint64(9753), false)
+	clearEnvironment()
+
+	assert.Nil(t, err)
+	assert.Equal(t, CheckStateWarning, status)
+	assert.Equal(t, "CheckOutput\n", output)
+}
+
+// Test check error
+func TestGoCheck_Execute_CheckError(t *testing.T) {
+	clearEnvironment()
+	status, output, err := goCheckExecuteUtil(t, []string{}, func() (int, string, error) {
+		return CheckStateOK, "ignored", fmt.Errorf("check error")
+	}, "Default1", uint64(33333), false)
+
+	assert.Nil(t, err)
+	assert.Equal(t, CheckStateUnknown, status)
+	assert.Equal(t, "error executing check: check error\n", output)
+}
+
+// Test invalid command line arguments
+func TestGoCheck_Execute_InvalidArgs(t *testing.T) {
+	checkCalled := false
+	clearEnvironment()
+	status, _, err := goCheckExecuteUtil(t, []string{"--arg2", "abc"}, func() (int, string, error) {
+		checkCalled = true
+		return CheckStateOK, "", nil
+	}, "Default1", uint64(0), false)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, -1, status)
+	assert.False(t, checkCalled)
+}
+
+func goCheckWithValidationExecuteUtil(validationFunction func() error, executeFunction func() error) (int, string, error) {
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goCheck := NewGoCheckWithValidation(&defaultCheckConfig, options, validationFunction, executeFunction)
+	goCheck.cmdArgs.SetArgs([]string{})
+
+	var output bytes.Buffer
+	exitStatus := -1
+	goCheck.outputWriter = &output
+	goCheck.exitFunction = func(status int) {
+		exitStatus = status
+	}
+	err := goCheck.Execute()
+
+	return exitStatus, output.String(), err
+}
+
+// Test execute success maps to OK
+func TestGoCheckWithValidation_Execute(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	status, _, err := goCheckWithValidationExecuteUtil(func() error {
+		validateCalled = true
+		return nil
+	}, func() error {
+		executeCalled = true
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, CheckStateOK, status)
+	assert.True(t, validateCalled)
+	assert.True(t, executeCalled)
+}
+
+// Test execute error maps to CRITICAL
+func TestGoCheckWithValidation_Execute_ExecuteError(t *testing.T) {
+	clearEnvironment()
+	status, output, err := goCheckWithValidationExecuteUtil(func() error {
+		return nil
+	}, func() error {
+		return fmt.Errorf("execution error")
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, CheckStateCritical, status)
+	assert.Equal(t, "error executing check: execution error\n", output)
+}
+
+// Test validation error
+func TestGoCheckWithValidation_Execute_ValidationError(t *testing.T) {
+	executeCalled := false
+	clearEnvironment()
+	status, output, err := goCheckWithValidationExecuteUtil(func() error {
+		return fmt.Errorf("validation error")
+	}, func() error {
+		executeCalled = true
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, CheckStateUnknown, status)
+	assert.Equal(t, "error validating input: validation error\n", output)
+	assert.False(t, executeCalled)
+}
+
+func TestGoCheck_SetOutputWriter(t *testing.T) {
+	var output bytes.Buffer
+	goCheck := NewGoCheck(&defaultCheckConfig, []*HandlerConfigOption{}, func() (int, string, error) {
+		return CheckStateWarning, "disk almost full", nil
+	})
+	goCheck.SetOutputWriter(&output)
+	goCheck.exitFunction = func(status int) {}
+	goCheck.cmdArgs.SetArgs([]string{})
+	err := goCheck.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "disk almost full\n", output.String())
+}
+
+func TestGoCheck_SetErrorWriter(t *testing.T) {
+	var errorOutput bytes.Buffer
+	goCheck := NewGoCheck(&defaultCheckConfig, []*HandlerConfigOption{}, func() (int, string, error) {
+		return CheckStateOK, "", nil
+	})
+	goCheck.SetErrorWriter(&errorOutput)
+	goCheck.cmdArgs.SetArgs([]string{"--unknown"})
+	err := goCheck.Execute()
+
+	assert.EqualError(t, err, "unknown flag: --unknown")
+	assert.Contains(t, errorOutput.String(), "Error: unknown flag: --unknown")
+}
+
+// Test the options are read from the --config file and a panicking check
+// reports the UNKNOWN status
+func TestGoCheck_Execute_ConfigFileAndPanic(t *testing.T) {
+	clearEnvironment()
+	status, output, err := goCheckExecuteUtil(t, []string{"--config", "test/config.yaml"},
+		func() (int, string, error) {
+			panic("check panic")
+		}, "value-config1", uint64(2468), true)
+
+	assert.Nil(t, err)
+	assert.Equal(t, CheckStateUnknown, status)
+	assert.Equal(t, "error executing check: handler panicked: check panic\n", output)
+}
@@ -0,0 +1,129 @@
+package sensu
+
+import (
+	"context"
+	"fmt"
+	"github.com/sensu/sensu-enterprise-go-plugin/args"
+	"github.com/sensu/sensu-go/types"
+	"io"
+	"os"
+)
+
+// Exit statuses of a filter
+const (
+	FilterStatusAllow = 0
+	FilterStatusDeny  = 1
+)
+
+// GoFilter reads a Sensu event from stdin, resolves its options exactly like
+// GoHandler and exits with FilterStatusAllow when its filter function keeps the
+// event, FilterStatusDeny when it drops it.
+type GoFilter struct {
+	optionResolver
+	sensuEvent         *types.Event
+	validationFunction func(event *types.Event) error
+	filterFunction     func(event *types.Event) (bool, error)
+	eventReader        io.Reader
+	eventFile          string
+	outputWriter       io.Writer
+	exitFunction       func(int)
+	inputFormat        string
+	showVersion        bool
+}
+
+// NewGoFilter creates a GoFilter with the given configuration, options,
+// validation function and filter function. The filter function returns true to
+// allow the event and false to deny it.
+func NewGoFilter(config *HandlerConfig, options []*HandlerConfigOption,
+	validationFunction func(event *types.Event) error, filterFunction func(event *types.Event) (bool, error)) *GoFilter {
+	goFilter := &GoFilter{
+		optionResolver:     newOptionResolver(config, options, os.Stderr),
+		sensuEvent:         nil,
+		validationFunction: validationFunction,
+		filterFunction:     filterFunction,
+		eventReader:        os.Stdin,
+		outputWriter:       os.Stdout,
+		exitFunction:       os.Exit,
+	}
+	cmdArgs := args.NewArgs(config.Name, config.Short, goFilter.cobraExecute)
+	cmdArgs.AddCommand("annotations", "Print the keyspace annotations supported by this filter",
+		goFilter.printAnnotations)
+	cmdArgs.PersistentStringVarP(&goFilter.eventFile, "event-file", "", "",
+		"Read the event from this file instead of stdin")
+	cmdArgs.PersistentStringVarP(&goFilter.inputFormat, "input-format", "", InputFormatJSON,
+		"Format of the event read from stdin or the event file (json or yaml)")
+	goFilter.registerFlags(cmdArgs, true)
+
+	return goFilter
+}
+
+// Execute parses the command line arguments, runs the filter and exits with
+// its decision. An error is returned, and the event should be dropped, when the
+// event or the options are invalid or the filter function fails.
+func (goFilter *GoFilter) Execute() error {
+	// Setup arguments
+	err := goFilter.registerOptions()
+	if err != nil {
+		return err
+	}
+	registerVersionFlag(goFilter.cmdArgs, goFilter.options, &goFilter.showVersion)
+
+	// This will call cobraExecute so put the rest of the logic in there
+	return goFilter.cmdArgs.Execute()
+}
+
+// SetOutputWriter sets the writer receiving the output written to stdout by
+// default, such as the annotations.
+func (goFilter *GoFilter) SetOutputWriter(writer io.Writer) {
+	goFilter.outputWriter = writer
+}
+
+// printAnnotations prints the annotations subcommand output to stdout
+func (goFilter *GoFilter) printAnnotations(_ []string) error {
+	goFilter.config = overrideKeyspace(goFilter.config, goFilter.keyspace)
+	return writeAnnotations(goFilter.outputWriter, goFilter.config, goFilter.options)
+}
+
+func (goFilter *GoFilter) cobraExecute(_ []string) error {
+	if goFilter.showVersion {
+		return writeVersion(goFilter.outputWriter, goFilter.config)
+	}
+
+	// Read Sensu event
+	reader, closeReader, err := eventFileReader(goFilter.eventFile, goFilter.eventReader)
+	if err != nil {
+		return err
+	}
+	defer closeReader()
+
+	sensuEvent, err := readEvent(reader, goFilter.config, goFilter.inputFormat)
+	if err != nil {
+		return withEventSource(err, goFilter.eventFile)
+	}
+	goFilter.sensuEvent = sensuEvent
+
+	// Resolve the options and validate the input
+	err = goFilter.resolve(goFilter.sensuEvent, withContext(goFilter.validationFunction))
+	if err != nil {
+		return err
+	}
+
+	// Decide whether to keep the event using filterFunction
+	var allow bool
+	err = recoverHandler(context.Background(), goFilter.sensuEvent, func(_ context.Context, event *types.Event) error {
+		var filterErr error
+		allow, filterErr = goFilter.filterFunction(event)
+		return filterErr
+	})
+	if err != nil {
+		return fmt.Errorf("error filtering event: %s", err)
+	}
+
+	if allow {
+		goFilter.exitFunction(FilterStatusAllow)
+	} else {
+		goFilter.exitFunction(FilterStatusDeny)
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package sensu
+
+import (
+	"fmt"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestNewGoFilter(t *testing.T) {
+	options := getDefaultOptions()
+	goFilter := NewGoFilter(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) (bool, error) {
+		return true, nil
+	})
+
+	assert.NotNil(t, goFilter)
+	assert.Equal(t, options, goFilter.options)
+	assert.Equal(t, &defaultHandlerConfig, goFilter.config)
+	assert.NotNil(t, goFilter.validationFunction)
+	assert.NotNil(t, goFilter.filterFunction)
+	assert.Nil(t, goFilter.sensuEvent)
+	assert.Equal(t, os.Stdin, goFilter.eventReader)
+	assert.NotNil(t, goFilter.exitFunction)
+	assert.NotNil(t, goFilter.cmdArgs)
+}
+
+func goFilterExecuteUtil(t *testing.T, eventFile string, validationFunction func(*types.Event) error,
+	filterFunction func(*types.Event) (bool, error),
+	expectedValue1 interface{}, expectedValue2 interface{}, expectedValue3 interface{}) (int, error) {
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goFilter := NewGoFilter(&defaultHandlerConfig, options, validationFunction, filterFunction)
+	goFilter.cmdArgs.SetArgs([]string{})
+	goFilter.SetErrorWriter(ioutil.Discard)
+
+	// Replace stdin reader with file reader and capture the exit status
+	exitStatus := -1
+	goFilter.eventReader = getFileReader(eventFile)
+	goFilter.exitFunction = func(status int) {
+		exitStatus = status
+	}
+	err := goFilter.Execute()
+
+	assert.Equal(t, expectedValue1, values.arg1)
+	assert.Equal(t, expectedValue2, values.arg2)
+	assert.Equal(t, expectedValue3, values.arg3)
+
+	return exitStatus, err
+}
+
+// Test allowing the event, the filter function sees the event overrides
+func TestGoFilter_Execute_Allow(t *testing.T) {
+	var filteredCheck string
+	clearEnvironment()
+	status, err := goFilterExecuteUtil(t, "test/event-check-override.json", func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) (bool, error) {
+		filteredCheck = event.Check.Name
+		return true, nil
+	}, "value-check1", uint64(1357), false)
+
+	assert.Nil(t, err)
+	assert.Equal(t, FilterStatusAllow, status)
+	assert.Equal(t, "check-nginx", filteredCheck)
+}
+
+// Test denying the event
+func TestGoFilter_Execute_Deny(t *testing.T) {
+	clearEnvironment()
+	status, err := goFilterExecuteUtil(t, "test/event-no-override.json", func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) (bool, error) {
+		return false, nil
+	}, "Default1", uint64(33333), false)
+
+	assert.Nil(t, err)
+	assert.Equal(t, FilterStatusDeny, status)
+}
+
+// Test filter, validation and event errors are returned without a decision
+func TestGoFilter_Execute_Errors(t *testing.T) {
+	filterCalled := false
+	clearEnvironment()
+	status, err := goFilterExecuteUtil(t, "test/event-no-override.json", func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) (bool, error) {
+		return true, fmt.Errorf("filter error")
+	}, "Default1", uint64(33333), false)
+
+	assert.EqualError(t, err, "error filtering event: filter error")
+	assert.Equal(t, -1, status)
+
+	status, err = goFilterExecuteUtil(t, "test/event-no-override.json", func(event *types.Event) error {
+		return fmt.Errorf("validation error")
+	}, func(event *types.Event) (bool, error) {
+		filterCalled = true
+		return true, nil
+	}, "Default1", uint64(33333), false)
+
+	assert.EqualError(t, err, "error validating input: validation error")
+	assert.Equal(t, -1, status)
+	assert.False(t, filterCalled)
+
+	status, err = goFilterExecuteUtil(t, "test/event-no-entity.json", func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) (bool, error) {
+		filterCalled = true
+		return true, nil
+	}, "Default1", uint64(33333), false)
+
+	assert.EqualError(t, err, "entity is missing from event")
+	assert.Equal(t, -1, status)
+	assert.False(t, filterCalled)
+}
+
+// Test the options are resolved like GoHandler's, from the --config file and
+// through the secret resolver, and panics are recovered
+func TestGoFilter_Execute_SharedResolution(t *testing.T) {
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+	options[0].Secret = true
+
+	goFilter := NewGoFilter(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) (bool, error) {
+		return true, nil
+	})
+	goFilter.cmdArgs.SetArgs([]string{"--config", "test/config.yaml"})
+	goFilter.SetErrorWriter(ioutil.Discard)
+	goFilter.SetSecretResolver(&fakeSecretResolver{secrets: map[string]string{"value-config1": "s3cr3t"}})
+	goFilter.eventReader = getFileReader("test/event-no-override.json")
+	goFilter.exitFunction = func(status int) {}
+
+	err := goFilter.Execute()
+	assert.Nil(t, err)
+	assert.Equal(t, "s3cr3t", values.arg1)
+	assert.Equal(t, uint64(2468), values.arg2)
+	assert.Equal(t, true, values.arg3)
+
+	status, err := goFilterExecuteUtil(t, "test/event-no-override.json", func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) (bool, error) {
+		panic("filter panic")
+	}, "Default1", uint64(33333), false)
+	assert.EqualError(t, err, "error filtering event: handler panicked: filter panic")
+	assert.Equal(t, -1, status)
+}
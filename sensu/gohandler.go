@@ -1,17 +1,20 @@
 package sensu
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/sensu/sensu-enterprise-go-plugin/args"
 	"github.com/sensu/sensu-go/types"
 	"io"
-	"io/ioutil"
-	"log"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
-	"path"
-	"strconv"
+	"regexp"
+	"strings"
+	"time"
 )
 
 type HandlerConfigOption struct {
@@ -22,66 +25,318 @@ type HandlerConfigOption struct {
 	Shorthand string      // short command line argument
 	Default   interface{} // default value
 	Usage     string
+	Example   string // sample value used when generating documentation
+	// Parse replaces the built-in parsing with a custom function receiving the
+	// resolved string value, it is responsible for assigning the value itself
+	Parse func(string) error
+	// Validate is called with the resolved value, before the validation
+	// function, to reject values that parsed correctly but are not acceptable
+	Validate func(interface{}) error
+	// Required options must be set by an annotation, the command line or the
+	// environment, the Default does not satisfy them and may be left nil
+	Required bool
+	// AllowedValues restricts the resolved value to one of these values when
+	// not empty, CaseInsensitive ignores the case when comparing them
+	AllowedValues   []string
+	CaseInsensitive bool
+	// Pattern is a regular expression the resolved value must match when not
+	// empty
+	Pattern string
+	// TemplateString makes sure the resolved value parses as a Go template
+	TemplateString bool
+	// Keyspace overrides the handler Keyspace for the annotations and labels
+	// of this option
+	Keyspace string
+	// FullAnnotationKey is the complete annotation and label key of this
+	// option, replacing the one built from the Keyspace and the Path
+	FullAnnotationKey string
+	// Secret string options resolved from the command line, the event or a
+	// custom source hold the name of the environment variable containing the
+	// value, keeping secrets out of annotations
+	Secret bool
+
+	// EnvAlternatives are environment variables read, in order, when Env is
+	// not set or empty
+	EnvAlternatives []string
+	// Encoding is how the values of []byte options are decoded, EncodingHex
+	// or EncodingBase64, the raw string bytes are used when it is empty
+	Encoding string
+	// RequireScheme restricts the scheme of url.URL options to one of these
+	// schemes when not empty
+	RequireScheme []string
+
+	rawValue string         // resolved string value for the options isRawOption accepts
+	source   string         // source of the resolved value
+	env      string         // environment variable the value is read from
+	pattern  *regexp.Regexp // compiled Pattern
 }
 
+// ValidationFailureMode defines how a validation function error is handled
+type ValidationFailureMode string
+
+const (
+	// ValidationFailureError aborts the execution on a validation error (default)
+	ValidationFailureError ValidationFailureMode = "error"
+	// ValidationFailureWarn logs the validation error and continues the execution
+	ValidationFailureWarn ValidationFailureMode = "warn"
+)
+
 type HandlerConfig struct {
-	Name     string
-	Short    string
-	Timeout  uint64
-	Keyspace string
+	Name                  string
+	Short                 string
+	Version               string
+	Timeout               uint64
+	Keyspace              string
+	ValidationFailureMode ValidationFailureMode
+	// MaxEventIntervals rejects events older than this many check intervals,
+	// zero disables the check
+	MaxEventIntervals uint32
+	// SkipCheckValidation, SkipEntityValidation and SkipTimestampValidation
+	// accept events without a valid check, entity or timestamp, such as the
+	// events of metric-only sources
+	SkipCheckValidation     bool
+	SkipEntityValidation    bool
+	SkipTimestampValidation bool
+	// UseLabels also looks for configuration overrides in the check and entity
+	// labels. The check has priority over the entity and, for each of them,
+	// annotations have priority over labels.
+	UseLabels bool
+	// DebugDiff logs the differences between the event and the output of a
+	// mutator
+	DebugDiff bool
+	// ValidationExitCode and ExecutionExitCode are the exit codes ExitCode
+	// returns when the validation or execute function fails, 1 when unset
+	ValidationExitCode int
+	ExecutionExitCode  int
+	// ShutdownGracePeriod is how long the handler has to return once its
+	// context is cancelled by SIGTERM or SIGINT before the process exits with
+	// status 1, DefaultShutdownGracePeriod when unset
+	ShutdownGracePeriod time.Duration
+	// CaseInsensitiveEnv reads an option from an environment variable whose
+	// name only differs from its Env by case, such as env_1 for ENV_1, when
+	// the exact name is not set
+	CaseInsensitiveEnv bool
+	// StrictKeyspace rejects the events carrying an annotation or label under
+	// the keyspace that no option reads, such as a misspelled option path
+	StrictKeyspace bool
+	// BatchMode reads newline-delimited JSON events, one per line, and runs
+	// the handler for each of them, like the --batch flag
+	BatchMode bool
+	// BatchFailFast stops a batch at the first event failing, by default the
+	// remaining events are still handled and the errors aggregated
+	BatchFailFast bool
+	// DedupBatch handles only the latest event, by timestamp, of the events of
+	// a batch sharing the same namespace, entity and check
+	DedupBatch bool
+	// RetryCount retries the execute function up to this many times when it
+	// fails, waiting RetryBackoff before the first retry and doubling the wait
+	// after each one. Validation errors are not retried.
+	RetryCount   uint32
+	RetryBackoff time.Duration
+	// NamespaceLookup provides namespace-wide option values, ranking right
+	// below the command line arguments. It is called with the namespace
+	// of the event entity and the annotation key of the option, and returns
+	// false when the namespace does not set it.
+	NamespaceLookup func(namespace, key string) (string, bool)
 }
 
 type GoHandler struct {
-	config             *HandlerConfig
-	options            []*HandlerConfigOption
-	sensuEvent         *types.Event
-	validationFunction func(event *types.Event) error
-	executeFunction    func(event *types.Event) error
-	eventReader        io.Reader
-	cmdArgs            *args.Args
+	optionResolver
+	sensuEvent           *types.Event
+	eventKey             string
+	validationFunction   func(ctx context.Context, event *types.Event) error
+	executeFunction      func(ctx context.Context, event *types.Event) error
+	eventReader          io.Reader
+	eventFile            string
+	optionsRegistered    bool
+	metricsSink          MetricsSink
+	logLevel             string
+	logger               Logger
+	cleanupFunction      func() error
+	deadlineOverrunGrace time.Duration
+	inputFormat          string
+	dryRun               bool
+	batchMode            bool
+	validateOnly         bool
+	showVersion          bool
+	outputFormat         string
+	outputWriter         io.Writer
+	customLogger         bool
+	serveAddr            string
+	servePath            string
+	listenFunction       func(addr string, handler http.Handler) error
+	exitFunction         func(int)
+	notifySignals        func(signals chan<- os.Signal)
+}
+
+// MetricsSink receives the counters emitted while executing a handler
+type MetricsSink interface {
+	IncrCounter(name string, labels map[string]string)
+}
+
+// GoHandlerOption configures a GoHandler when it is created
+type GoHandlerOption func(goHandler *GoHandler)
+
+// WithEventReader makes the handler read the event from reader instead of
+// stdin, for instance from the body of an HTTP request.
+func WithEventReader(reader io.Reader) GoHandlerOption {
+	return func(goHandler *GoHandler) {
+		goHandler.eventReader = reader
+	}
+}
+
+// WithErrorWriter makes the handler write its diagnostic and error messages
+// to writer instead of stderr, see SetErrorWriter.
+func WithErrorWriter(writer io.Writer) GoHandlerOption {
+	return func(goHandler *GoHandler) {
+		goHandler.SetErrorWriter(writer)
+	}
+}
+
+// WithOutputWriter makes the handler write its output, such as the annotations
+// and the dry run values, to writer instead of stdout.
+func WithOutputWriter(writer io.Writer) GoHandlerOption {
+	return func(goHandler *GoHandler) {
+		goHandler.outputWriter = writer
+	}
+}
+
+// WithArgs makes the handler parse arguments instead of the command line of
+// the process, for instance in tests.
+func WithArgs(arguments []string) GoHandlerOption {
+	return func(goHandler *GoHandler) {
+		goHandler.cmdArgs.SetArgs(arguments)
+	}
+}
+
+// WithConfigFile makes the handler read option values from configFile, as with
+// the --config flag, which replaces it when given. It also applies to the
+// stages called on their own, which do not parse the command line.
+func WithConfigFile(configFile string) GoHandlerOption {
+	return func(goHandler *GoHandler) {
+		goHandler.configFile = configFile
+	}
+}
+
+// WithKeyspace makes the handler read the annotations and labels from keyspace,
+// as with the --keyspace flag, which replaces it when given.
+func WithKeyspace(keyspace string) GoHandlerOption {
+	return func(goHandler *GoHandler) {
+		goHandler.keyspace = keyspace
+	}
+}
+
+// WithExitFunction replaces os.Exit, called by Execute when the validation or
+// execute function returns a StatusError.
+func WithExitFunction(exitFunction func(status int)) GoHandlerOption {
+	return func(goHandler *GoHandler) {
+		goHandler.exitFunction = exitFunction
+	}
 }
 
 func NewGoHandler(config *HandlerConfig, options []*HandlerConfigOption,
-	validationFunction func(event *types.Event) error, executeFunction func(event *types.Event) error) *GoHandler {
+	validationFunction func(event *types.Event) error, executeFunction func(event *types.Event) error,
+	handlerOptions ...GoHandlerOption) *GoHandler {
+	return NewGoHandlerWithContext(config, options,
+		func(_ context.Context, event *types.Event) error {
+			return validationFunction(event)
+		}, func(_ context.Context, event *types.Event) error {
+			return executeFunction(event)
+		}, handlerOptions...)
+}
+
+// NewGoHandlerWithContext creates a GoHandler whose validation and execution
+// functions receive a context. The context is cancelled once the configured
+// Timeout (in seconds) elapses.
+func NewGoHandlerWithContext(config *HandlerConfig, options []*HandlerConfigOption,
+	validationFunction func(ctx context.Context, event *types.Event) error,
+	executeFunction func(ctx context.Context, event *types.Event) error,
+	handlerOptions ...GoHandlerOption) *GoHandler {
 	goHandler := &GoHandler{
-		config:             config,
-		options:            options,
+		optionResolver:     newOptionResolver(config, options, os.Stderr),
 		sensuEvent:         nil,
 		validationFunction: validationFunction,
 		executeFunction:    executeFunction,
 		eventReader:        os.Stdin,
+		logger:             NewJSONLogger(os.Stderr),
+		outputWriter:       os.Stdout,
+		servePath:          DefaultServePath,
+		listenFunction:     http.ListenAndServe,
+		exitFunction:       os.Exit,
+		notifySignals:      notifyTerminationSignals,
 	}
 	cmdArgs := args.NewArgs(config.Name, config.Short, goHandler.cobraExecute)
-	goHandler.cmdArgs = cmdArgs
+	cmdArgs.AddCommand("annotations", "Print the keyspace annotations supported by this handler",
+		goHandler.printAnnotations)
+	cmdArgs.PersistentStringVarP(&goHandler.eventFile, "event-file", "", "",
+		"Read the event from this file instead of stdin")
+	cmdArgs.PersistentStringVarP(&goHandler.inputFormat, "input-format", "", InputFormatJSON,
+		"Format of the event read from stdin or the event file (json or yaml)")
+	goHandler.registerFlags(cmdArgs, true)
+	cmdArgs.PersistentStringVarP(&goHandler.logLevel, "log-level", "", "",
+		"Log the handler execution to stderr at this level (error, info or debug)")
+	cmdArgs.BoolVarP(&goHandler.dryRun, "dry-run", "", "", false,
+		"Read the event, resolve the options and validate the input without executing the handler")
+	cmdArgs.BoolVarP(&goHandler.batchMode, "batch", "", "", false,
+		"Read newline-delimited JSON events and run the handler for each of them")
+	cmdArgs.StringVarP(&goHandler.outputFormat, "output", "", "", "",
+		"Print a summary of the handler execution to stdout in this format (json)")
+
+	for _, handlerOption := range handlerOptions {
+		handlerOption(goHandler)
+	}
 
 	return goHandler
 }
 
-func (goHandler *GoHandler) Execute() error {
-	// Setup arguments
-	for _, option := range goHandler.options {
-		if option.Value == nil {
-			return fmt.Errorf("Option value must not be nil for option %s", option.Argument)
+// NewGoHandlerMulti creates a GoHandler running the execute functions in order.
+// With failFast the first error stops the execution, otherwise every function
+// runs and their errors are combined.
+func NewGoHandlerMulti(config *HandlerConfig, options []*HandlerConfigOption,
+	validationFunction func(event *types.Event) error, executeFunctions []func(event *types.Event) error,
+	failFast bool, handlerOptions ...GoHandlerOption) *GoHandler {
+	return NewGoHandler(config, options, validationFunction, func(event *types.Event) error {
+		var errs []error
+		for i, executeFunction := range executeFunctions {
+			if err := executeFunction(event); err != nil {
+				if failFast {
+					return fmt.Errorf("function %d: %w", i+1, err)
+				}
+				errs = append(errs, fmt.Errorf("function %d: %w", i+1, err))
+			}
+		}
+		if len(errs) > 0 {
+			return &MultiError{Errors: errs}
 		}
+		return nil
+	}, handlerOptions...)
+}
 
-		switch (option.Value).(type) {
-		case *string:
-			valuePtr, _ := option.Value.(*string)
-			goHandler.cmdArgs.StringVarP(valuePtr, option.Argument, option.Shorthand, option.Env,
-				option.Default.(string), option.Usage)
-		case *uint64:
-			valuePtr, _ := option.Value.(*uint64)
-			goHandler.cmdArgs.Uint64VarP(valuePtr, option.Argument, option.Shorthand, option.Env,
-				option.Default.(uint64), option.Usage)
-		case *bool:
-			valuePtr, _ := option.Value.(*bool)
-			goHandler.cmdArgs.BoolVarP(valuePtr, option.Argument, option.Shorthand, option.Env,
-				option.Default.(bool), option.Usage)
+// Execute parses the command line arguments, reads the event and runs the
+// handler. When the validation or execute function returns a StatusError the
+// process exits with its status, other errors are returned.
+func (goHandler *GoHandler) Execute() (err error) {
+	defer func() {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			goHandler.exitFunction(statusErr.Status)
 		}
+	}()
+
+	if goHandler.cleanupFunction != nil {
+		defer func() {
+			err = cleanupError(err, goHandler.cleanupFunction())
+		}()
+	}
+
+	// Setup arguments
+	err = goHandler.setupOptions()
+	if err != nil {
+		return err
 	}
 
 	// This will call cobraExecute so put the rest of the logic in there
-	err := goHandler.cmdArgs.Execute()
+	err = goHandler.cmdArgs.Execute()
 	if err != nil {
 		return err
 	}
@@ -89,130 +344,355 @@ func (goHandler *GoHandler) Execute() error {
 	return nil
 }
 
-func (goHandler *GoHandler) readSensuEvent() error {
-	eventJSON, err := ioutil.ReadAll(goHandler.eventReader)
-	if err != nil {
-		return fmt.Errorf("Failed to read STDIN: %s", err)
+// SetCleanup sets a function called when Execute returns, whether the handler
+// succeeded or failed, to release the resources it acquired.
+func (goHandler *GoHandler) SetCleanup(cleanupFunction func() error) {
+	goHandler.cleanupFunction = cleanupFunction
+}
+
+// cleanupError combines the error returned by the handler with the error
+// returned by the cleanup function
+func cleanupError(err error, cleanupErr error) error {
+	if cleanupErr == nil {
+		return err
 	}
+	if err == nil {
+		return fmt.Errorf("error cleaning up handler: %s", cleanupErr)
+	}
+	return fmt.Errorf("%w (error cleaning up handler: %s)", err, cleanupErr)
+}
 
-	sensuEvent := &types.Event{}
-	err = json.Unmarshal(eventJSON, sensuEvent)
-	if err != nil {
-		return fmt.Errorf("Failed to unmarshal STDIN data: %s", err)
+// SetMetricsSink sets the sink receiving an "option_source" counter, labelled
+// with the option and the source its value was resolved from, on every run.
+func (goHandler *GoHandler) SetMetricsSink(metricsSink MetricsSink) {
+	goHandler.metricsSink = metricsSink
+}
+
+// SetOutputWriter sets the writer receiving the output written to stdout by
+// default, such as the annotations and the dry run values.
+func (goHandler *GoHandler) SetOutputWriter(writer io.Writer) {
+	goHandler.outputWriter = writer
+}
+
+// SetErrorWriter sets the writer receiving the diagnostic and error messages
+// written to stderr by default, including the command line usage and the JSON
+// logs unless SetLogger is called.
+func (goHandler *GoHandler) SetErrorWriter(writer io.Writer) {
+	goHandler.errorWriter = writer
+	goHandler.cmdArgs.SetOutput(writer)
+	if !goHandler.customLogger {
+		goHandler.logger = NewJSONLogger(writer)
 	}
+}
 
-	if err = validateEvent(sensuEvent); err != nil {
-		return err
+// SetLogger sets the logger receiving the structured logs enabled by the
+// --log-level flag. Logs are written to stderr as JSON by default.
+func (goHandler *GoHandler) SetLogger(logger Logger) {
+	goHandler.logger = logger
+	goHandler.customLogger = true
+}
+
+// log sends an entry to the logger if its level is enabled by --log-level
+func (goHandler *GoHandler) log(level LogLevel, message string, fields map[string]interface{}) {
+	if goHandler.logger != nil && logLevelEnabled(LogLevel(goHandler.logLevel), level) {
+		goHandler.logger.Log(level, message, fields)
+	}
+}
+
+// OptionsAsEnv returns the current option values as KEY=VALUE pairs suitable
+// for exec.Cmd.Env. The option's Env is used as the key, or a name derived
+// from its Argument if it has none. Options without a Value are skipped.
+func (goHandler *GoHandler) OptionsAsEnv() []string {
+	env := make([]string, 0, len(goHandler.options))
+	for _, opt := range goHandler.options {
+		if opt.Value == nil {
+			continue
+		}
+		key := opt.Env
+		if key == "" {
+			key = strings.ToUpper(strings.Replace(opt.Argument, "-", "_", -1))
+		}
+		value := optionValue(opt)
+		if sliceValue, ok := value.([]string); ok {
+			value = formatDefault(sliceValue)
+		} else if bytesValue, ok := value.([]byte); ok {
+			value = encodeBytes(opt.Encoding, bytesValue)
+		} else if ipNetValue, ok := value.(net.IPNet); ok {
+			value = ipNetValue.String()
+		} else if urlValue, ok := value.(url.URL); ok {
+			value = urlValue.String()
+		} else if mapValue, ok := value.(map[string]string); ok {
+			value = formatStringMap(mapValue)
+		} else if isJSONOption(opt) {
+			jsonValue, err := json.Marshal(value)
+			if err != nil {
+				continue
+			}
+			value = string(jsonValue)
+		}
+		env = append(env, fmt.Sprintf("%s=%v", key, value))
 	}
+	return env
+}
 
-	goHandler.sensuEvent = sensuEvent
+// printAnnotations prints the annotations subcommand output to stdout
+func (goHandler *GoHandler) printAnnotations(_ []string) error {
+	goHandler.config = overrideKeyspace(goHandler.config, goHandler.keyspace)
+	return writeAnnotations(goHandler.outputWriter, goHandler.config, goHandler.options)
+}
+
+// writeAnnotations writes a YAML annotations block containing the fully
+// qualified annotation key of every option that can be overridden, along with
+// a sample value taken from the option's Example or Default.
+func writeAnnotations(writer io.Writer, config *HandlerConfig, options []*HandlerConfigOption) error {
+	hasKeyspace := false
+	for _, opt := range options {
+		hasKeyspace = hasKeyspace || len(optionKeyspace(config, opt)) > 0 || len(opt.FullAnnotationKey) > 0
+	}
+	if !hasKeyspace {
+		return errors.New("no keyspace configured for this handler")
+	}
+
+	if _, err := fmt.Fprintln(writer, "annotations:"); err != nil {
+		return err
+	}
+	for _, opt := range options {
+		k := annotationKey(config, opt)
+		if len(k) == 0 {
+			continue
+		}
+		example := opt.Example
+		if example == "" && opt.Default != nil {
+			example = defaultString(opt)
+		}
+		if _, err := fmt.Fprintf(writer, "  %s: %q\n", k, example); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func validateEvent(event *types.Event) error {
-	if event.Timestamp <= 0 {
-		return errors.New("timestamp is missing or must be greater than zero")
+// Intentionally does nothing since we're only using cobra to read the command line arguments
+func (goHandler *GoHandler) cobraExecute(_ []string) error {
+	if goHandler.showVersion {
+		return writeVersion(goHandler.outputWriter, goHandler.config)
 	}
 
-	if event.Entity == nil {
-		return errors.New("entity is missing from event")
+	if err := checkLogLevel(LogLevel(goHandler.logLevel)); err != nil {
+		return err
 	}
 
-	if !event.HasCheck() {
-		return errors.New("check is missing from event")
+	if err := checkOutputFormat(goHandler.outputFormat); err != nil {
+		return err
 	}
 
-	if err := event.Entity.Validate(); err != nil {
+	// Both print JSON to stdout, which would not parse as a single document
+	if goHandler.dryRun && len(goHandler.outputFormat) > 0 {
+		return fmt.Errorf("--dry-run cannot be used with --output, both print to stdout")
+	}
+
+	if err := goHandler.loadFlags(); err != nil {
 		return err
 	}
 
-	if err := event.Check.Validate(); err != nil {
+	if len(goHandler.serveAddr) > 0 {
+		return goHandler.serve()
+	}
+
+	start := time.Now()
+	goHandler.log(LogLevelInfo, "handler started", map[string]interface{}{"handler": goHandler.config.Name})
+	err := goHandler.run()
+	duration := time.Since(start)
+
+	fields := map[string]interface{}{
+		"handler":  goHandler.config.Name,
+		"duration": duration.String(),
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+		goHandler.log(LogLevelError, "handler failed", fields)
+	} else {
+		goHandler.log(LogLevelInfo, "handler finished", fields)
+	}
+
+	if summaryErr := goHandler.printSummary(err, duration); summaryErr != nil && err == nil {
+		return summaryErr
+	}
+	return err
+}
+
+func (goHandler *GoHandler) run() error {
+	timeoutCtx, cancelTimeout := timeoutContext(goHandler.config.Timeout)
+	defer cancelTimeout()
+	ctx, cancel := goHandler.signalContext(timeoutCtx)
+	defer cancel()
+	goHandler.eventKey = ""
+
+	// Read Sensu event
+	eventData, err := goHandler.readSensuEventData()
+	if err != nil {
 		return err
 	}
 
-	return nil
+	if goHandler.config.BatchMode || goHandler.batchMode {
+		if goHandler.inputFormat != InputFormatJSON {
+			return fmt.Errorf("batch mode requires the %s input format", InputFormatJSON)
+		}
+		return goHandler.runBatch(ctx, splitEventLines(eventData))
+	}
+
+	if isEventBatch(eventData, goHandler.inputFormat) {
+		var eventsData []json.RawMessage
+		err = json.Unmarshal(eventData, &eventsData)
+		if err != nil {
+			return fmt.Errorf("Failed to unmarshal STDIN data: %s", err)
+		}
+		return goHandler.runBatch(ctx, eventsData)
+	}
+
+	sensuEvent, err := parseEvent(eventData, goHandler.config, goHandler.inputFormat)
+	if err != nil {
+		return err
+	}
+	goHandler.sensuEvent = sensuEvent
+	goHandler.eventKey = EventKey(sensuEvent)
+	goHandler.log(LogLevelDebug, "event read", map[string]interface{}{
+		"event": goHandler.eventKey,
+	})
+
+	// Resolve the options and validate the input
+	err = goHandler.resolveOptions(sensuEvent)
+	if err != nil {
+		return err
+	}
+	err = goHandler.validate(ctx)
+	if err != nil {
+		return err
+	}
+
+	return goHandler.executeEvent(ctx)
 }
 
-func configurationOverrides(config *HandlerConfig, options []*HandlerConfigOption, event *types.Event) error {
-	if config.Keyspace == "" {
+// executeEvent runs the execute function, unless in dry run or validation only
+// mode
+func (goHandler *GoHandler) executeEvent(ctx context.Context) error {
+	if goHandler.validateOnly {
 		return nil
 	}
-	for _, opt := range options {
-		if len(opt.Path) > 0 {
-			// compile the Annotation keyspace to look for configuration overrides
-			k := path.Join(config.Keyspace, opt.Path)
-			switch {
-			case len(event.Check.Annotations[k]) > 0:
-				err := setOptionValue(opt, event.Check.Annotations[k])
-				if err != nil {
-					return err
-				}
-				log.Printf("Overriding default handler configuration with value of \"Check.Annotations.%s\" (\"%s\")\n", k, event.Check.Annotations[k])
-			case len(event.Entity.Annotations[k]) > 0:
-				err := setOptionValue(opt, event.Entity.Annotations[k])
-				if err != nil {
-					return err
-				}
-				log.Printf("Overriding default handler configuration with value of \"Check.Annotations.%s\" (\"%s\")\n", k, event.Entity.Annotations[k])
-			}
-		}
+
+	if goHandler.dryRun {
+		goHandler.errorLog().Printf("Dry run, skipping the handler execution\n")
+		return goHandler.printResolvedValues()
 	}
+
+	// Execute handler logic using executeFunction
+	err := goHandler.executeWithRetry(ctx)
+	if err != nil {
+		return &ExecutionError{Err: err}
+	}
+
 	return nil
 }
 
-func setOptionValue(option *HandlerConfigOption, valueStr string) error {
-	switch option.Value.(type) {
-	case *string:
-		strOptionValue, ok := option.Value.(*string)
-		if ok {
-			*strOptionValue = valueStr
-		}
-	case *uint64:
-		uint64OptionPtrValue, ok := option.Value.(*uint64)
-		if ok {
-			parsedValue, err := strconv.ParseUint(valueStr, 10, 64)
-			if err != nil {
-				return fmt.Errorf("Error parsing %s into a uint64 for option %s", valueStr, option.Argument)
-			}
-			*uint64OptionPtrValue = parsedValue
-		}
-	case *bool:
-		boolOptionPtrValue, ok := option.Value.(*bool)
-		if ok {
-			parsedValue, err := strconv.ParseBool(valueStr)
-			if err != nil {
-				return fmt.Errorf("Error parsing %s into a bool for option %s", valueStr, option.Argument)
-			}
-			*boolOptionPtrValue = parsedValue
+// executeWithRetry calls the execute function, retrying it up to RetryCount
+// times with an exponential backoff while it fails and the context is not done
+func (goHandler *GoHandler) executeWithRetry(ctx context.Context) error {
+	backoff := goHandler.config.RetryBackoff
+	err := goHandler.execute(ctx)
+	for attempt := uint32(1); err != nil && attempt <= goHandler.config.RetryCount; attempt++ {
+		goHandler.log(LogLevelInfo, "execution failed, retrying", map[string]interface{}{
+			"error":   err.Error(),
+			"attempt": attempt,
+			"backoff": backoff.String(),
+		})
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
 		}
+		backoff *= 2
+
+		err = goHandler.execute(ctx)
 	}
-	return nil
+
+	return err
 }
 
-// Intentionally does nothing since we're only using cobra to read the command line arguments
-func (goHandler *GoHandler) cobraExecute(_ []string) error {
-	// Read Sensu event
-	err := goHandler.readSensuEvent()
+// execute calls the execute function once, warning if it overran the context
+// deadline
+func (goHandler *GoHandler) execute(ctx context.Context) error {
+	err := recoverHandler(ctx, goHandler.sensuEvent, goHandler.executeFunction)
+	goHandler.checkDeadlineOverrun(ctx)
+	return err
+}
+
+// ResolveOptions resolves the option values from the defaults, the environment,
+// the config file, the custom sources and the overrides of the event, read
+// under the keyspace given with WithKeyspace or KeyspaceEnv, as well as the
+// command line when called by Execute. The secrets are then read and the
+// required options checked.
+func (goHandler *GoHandler) ResolveOptions(sensuEvent *types.Event) error {
+	err := goHandler.loadFlags()
 	if err != nil {
 		return err
 	}
 
-	// Override the configuration with the event information
-	err = configurationOverrides(goHandler.config, goHandler.options, goHandler.sensuEvent)
+	return goHandler.resolveOptions(sensuEvent)
+}
+
+// resolveOptions resolves the option values once the --keyspace and --config
+// flags are applied
+func (goHandler *GoHandler) resolveOptions(sensuEvent *types.Event) error {
+	err := goHandler.setupOptions()
 	if err != nil {
 		return err
 	}
+	goHandler.sensuEvent = sensuEvent
 
-	// Validate input using validateFunction
-	err = goHandler.validationFunction(goHandler.sensuEvent)
+	err = goHandler.resolveCommandLine()
 	if err != nil {
-		return fmt.Errorf("error validating input: %s", err)
+		return err
 	}
 
-	// Execute handler logic using executeFunction
-	err = goHandler.executeFunction(goHandler.sensuEvent)
+	return goHandler.resolveEvent()
+}
+
+// resolveEventAndValidate applies the custom sources and the event overrides to
+// the options resolved from the command line and the environment, then runs the
+// validation function
+func (goHandler *GoHandler) resolveEventAndValidate(ctx context.Context) error {
+	err := goHandler.resolveEvent()
+	if err != nil {
+		return err
+	}
+
+	return goHandler.validate(ctx)
+}
+
+// resolveEvent applies the custom sources and the event overrides to the
+// options resolved from the command line and the environment, then reads the
+// secrets and checks the required options are set
+func (goHandler *GoHandler) resolveEvent() error {
+	err := goHandler.resolveEventOptions(goHandler.sensuEvent)
 	if err != nil {
-		return fmt.Errorf("error executing handler: %s", err)
+		return err
+	}
+
+	for _, opt := range goHandler.options {
+		goHandler.log(LogLevelDebug, "option resolved", map[string]interface{}{
+			"option": opt.Argument,
+			"source": opt.source,
+			"value":  displayValue(opt, optionValue(opt)),
+		})
+	}
+
+	if goHandler.metricsSink != nil {
+		for _, opt := range goHandler.options {
+			goHandler.metricsSink.IncrCounter("option_source", map[string]string{
+				"option": opt.Argument,
+				"source": opt.source,
+			})
+		}
 	}
 
 	return nil
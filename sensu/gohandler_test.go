@@ -1,12 +1,17 @@
 package sensu
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/sensu/sensu-go/types"
 	"github.com/stretchr/testify/assert"
 	"io"
+	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 )
 
 type handlerValues struct {
@@ -73,177 +78,229 @@ func TestNewGoHandler(t *testing.T) {
 	assert.NotNil(t, goHandler.cmdArgs)
 }
 
-func TestSetOptionValue_String(t *testing.T) {
-	finalValue := ""
-	option := defaultOption1
-	option.Value = &finalValue
-	err := setOptionValue(&option, "abc")
+func TestNewGoHandler_WithEventReaderAndOutputWriter(t *testing.T) {
+	var executeCheck string
+	clearEnvironment()
+	eventJSON, err := ioutil.ReadFile("test/event-check-override.json")
 	assert.Nil(t, err)
-	assert.Equal(t, "abc", finalValue)
-}
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+	var output bytes.Buffer
 
-func TestSetOptionValue_EmptyString(t *testing.T) {
-	finalValue := ""
-	option := defaultOption1
-	option.Value = &finalValue
-	err := setOptionValue(&option, "")
+	goHandler := NewGoHandler(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) error {
+		executeCheck = event.Check.Name
+		return nil
+	}, WithEventReader(bytes.NewReader(eventJSON)), WithOutputWriter(&output))
+	goHandler.cmdArgs.SetArgs([]string{})
+	err = goHandler.Execute()
 	assert.Nil(t, err)
-	assert.Equal(t, "", finalValue)
-}
+	assert.Equal(t, "check-nginx", executeCheck)
+	assert.Equal(t, "value-check1", values.arg1)
 
-func TestSetOptionValue_ValidUint64(t *testing.T) {
-	var finalValue uint64
-	option := defaultOption1
-	option.Value = &finalValue
-	err := setOptionValue(&option, "123")
+	goHandler = NewGoHandler(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) error {
+		return nil
+	}, WithEventReader(bytes.NewReader(eventJSON)), WithOutputWriter(&output))
+	goHandler.cmdArgs.SetArgs([]string{"--dry-run"})
+	err = goHandler.Execute()
 	assert.Nil(t, err)
-	assert.Equal(t, uint64(123), finalValue)
+	assert.Contains(t, output.String(), `"arg1":"value-check1"`)
 }
 
-func TestSetOptionValue_InvalidUint64(t *testing.T) {
-	var finalValue uint64
-	option := defaultOption1
-	option.Value = &finalValue
-	err := setOptionValue(&option, "abc")
-	assert.NotNil(t, err)
-	assert.Equal(t, uint64(0), finalValue)
-}
+func TestGoHandler_SetOutputWriter(t *testing.T) {
+	var output bytes.Buffer
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
 
-func TestSetOptionValue_TrueBool(t *testing.T) {
-	var finalValue bool
-	option := defaultOption1
-	option.Value = &finalValue
-	err := setOptionValue(&option, "true")
-	assert.Nil(t, err)
-	assert.Equal(t, true, finalValue)
-}
+	goHandler := NewGoHandler(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) error {
+		return nil
+	})
+	goHandler.SetOutputWriter(&output)
+	goHandler.cmdArgs.SetArgs([]string{"annotations"})
+	err := goHandler.Execute()
 
-func TestSetOptionValue_FalseBool(t *testing.T) {
-	finalValue := true
-	option := defaultOption1
-	option.Value = &finalValue
-	err := setOptionValue(&option, "false")
 	assert.Nil(t, err)
-	assert.Equal(t, false, finalValue)
+	assert.Contains(t, output.String(), "sensu.io/plugins/segp/config/path1: \"Default1\"")
 }
 
-func TestSetOptionValue_InvalidBool(t *testing.T) {
-	var finalValue bool
-	option := defaultOption1
-	option.Value = &finalValue
-	err := setOptionValue(&option, "yes")
-	assert.NotNil(t, err)
-	assert.Equal(t, false, finalValue)
-}
-
-func goHandlerExecuteUtil(t *testing.T, handlerConfig *HandlerConfig, eventFile string, cmdLineArgs []string,
-	validationFunction func(*types.Event) error, executeFunction func(*types.Event) error,
-	expectedValue1 interface{}, expectedValue2 interface{}, expectedValue3 interface{}) error {
+func TestGoHandler_SetErrorWriter(t *testing.T) {
+	var errorOutput bytes.Buffer
+	clearEnvironment()
+	handlerConfig := defaultHandlerConfig
+	handlerConfig.ValidationFailureMode = ValidationFailureWarn
 	options := getDefaultOptions()
 	values := handlerValues{}
 	options[0].Value = &values.arg1
 	options[1].Value = &values.arg2
 	options[2].Value = &values.arg3
 
-	goHandler := NewGoHandler(handlerConfig, options, validationFunction, executeFunction)
-
-	if len(cmdLineArgs) > 0 {
-		goHandler.cmdArgs.SetArgs(cmdLineArgs)
-	} else {
-		goHandler.cmdArgs.SetArgs([]string{})
-	}
-
-	// Replace stdin reader with file reader
-	goHandler.eventReader = getFileReader(eventFile)
+	goHandler := NewGoHandler(&handlerConfig, options, func(event *types.Event) error {
+		return fmt.Errorf("invalid input")
+	}, func(event *types.Event) error {
+		return fmt.Errorf("execution failed")
+	})
+	goHandler.SetErrorWriter(&errorOutput)
+	goHandler.cmdArgs.SetArgs([]string{"--log-level", "error"})
+	goHandler.eventReader = getFileReader("test/event-check-override.json")
 	err := goHandler.Execute()
 
-	assert.Equal(t, expectedValue1, values.arg1)
-	assert.Equal(t, expectedValue2, values.arg2)
-	assert.Equal(t, expectedValue3, values.arg3)
+	assert.EqualError(t, err, "error executing handler: execution failed")
+	assert.Contains(t, errorOutput.String(), "Ignoring validation error: invalid input")
+	assert.Contains(t, errorOutput.String(), `"message":"handler failed"`)
+	assert.Contains(t, errorOutput.String(), "Error: error executing handler: execution failed")
 
-	return err
+	// A logger set with SetLogger is kept
+	var logs bytes.Buffer
+	errorOutput.Reset()
+	goHandler = NewGoHandler(&handlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) error {
+		return fmt.Errorf("execution failed")
+	})
+	goHandler.SetLogger(NewJSONLogger(&logs))
+	goHandler.SetErrorWriter(&errorOutput)
+	goHandler.cmdArgs.SetArgs([]string{"--log-level", "error"})
+	goHandler.eventReader = getFileReader("test/event-check-override.json")
+	_ = goHandler.Execute()
+
+	assert.Contains(t, logs.String(), `"message":"handler failed"`)
+	assert.NotContains(t, errorOutput.String(), `"message":"handler failed"`)
 }
 
-// Test check override
-func TestGoHandler_Execute_Check(t *testing.T) {
+func TestNewGoHandlerWithContext(t *testing.T) {
 	var validateCalled, executeCalled bool
 	clearEnvironment()
-	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-check-override.json", nil,
-		func(event *types.Event) error {
+	handlerConfig := defaultHandlerConfig
+	handlerConfig.Timeout = 1
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandlerWithContext(&handlerConfig, options,
+		func(ctx context.Context, event *types.Event) error {
 			validateCalled = true
-			assert.NotNil(t, event)
+			_, ok := ctx.Deadline()
+			assert.True(t, ok)
 			return nil
-		}, func(event *types.Event) error {
+		}, func(ctx context.Context, event *types.Event) error {
 			executeCalled = true
-			assert.NotNil(t, event)
-			return nil
-		},
-		"value-check1", uint64(1357), false)
-	assert.Nil(t, err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+				return nil
+			}
+		})
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.EqualError(t, err, "error executing handler: context deadline exceeded")
 	assert.True(t, validateCalled)
 	assert.True(t, executeCalled)
 }
 
-// Test check override with invalid value
-func TestGoHandler_Execute_CheckInvalidValue(t *testing.T) {
-	var validateCalled, executeCalled bool
-	clearEnvironment()
-	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-check-override-invalid-value.json", nil,
-		func(event *types.Event) error {
-			validateCalled = true
-			assert.NotNil(t, event)
-			return nil
-		}, func(event *types.Event) error {
-			executeCalled = true
-			assert.NotNil(t, event)
-			return nil
-		},
-		"value-check1", uint64(33333), false)
-	assert.NotNil(t, err)
-	assert.False(t, validateCalled)
-	assert.False(t, executeCalled)
+func TestNewGoHandlerMulti(t *testing.T) {
+	tests := []struct {
+		failFast      bool
+		expectedErr   string
+		expectedCalls []int
+	}{
+		{true, "error executing handler: function 2: slack unavailable", []int{1, 2}},
+		{false, "error executing handler: function 2: slack unavailable; function 4: ticket rejected", []int{1, 2, 3, 4}},
+	}
+
+	for _, test := range tests {
+		var calls []int
+		executeFunctions := []func(event *types.Event) error{
+			func(event *types.Event) error {
+				calls = append(calls, 1)
+				return nil
+			}, func(event *types.Event) error {
+				calls = append(calls, 2)
+				return fmt.Errorf("slack unavailable")
+			}, func(event *types.Event) error {
+				calls = append(calls, 3)
+				return nil
+			}, func(event *types.Event) error {
+				calls = append(calls, 4)
+				return fmt.Errorf("ticket rejected")
+			},
+		}
+
+		clearEnvironment()
+		goHandler := NewGoHandlerMulti(&defaultHandlerConfig, []*HandlerConfigOption{},
+			func(event *types.Event) error {
+				return nil
+			}, executeFunctions, test.failFast)
+		goHandler.cmdArgs.SetArgs([]string{})
+		goHandler.eventReader = getFileReader("test/event-no-override.json")
+		err := goHandler.Execute()
+
+		assert.EqualError(t, err, test.expectedErr)
+		assert.Equal(t, test.expectedCalls, calls)
+	}
 }
 
-// Test entity override
-func TestGoHandler_Execute_Entity(t *testing.T) {
-	var validateCalled, executeCalled bool
+func TestNewGoHandlerMulti_Success(t *testing.T) {
+	var calls int
+	executeFunction := func(event *types.Event) error {
+		calls++
+		return nil
+	}
+
 	clearEnvironment()
-	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-entity-override.json", nil,
+	goHandler := NewGoHandlerMulti(&defaultHandlerConfig, []*HandlerConfigOption{},
 		func(event *types.Event) error {
-			validateCalled = true
-			assert.NotNil(t, event)
 			return nil
-		}, func(event *types.Event) error {
-			executeCalled = true
-			assert.NotNil(t, event)
-			return nil
-		},
-		"value-entity1", uint64(2468), true)
+		}, []func(event *types.Event) error{executeFunction, executeFunction}, true)
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
 
 	assert.Nil(t, err)
-	assert.True(t, validateCalled)
-	assert.True(t, executeCalled)
+	assert.Equal(t, 2, calls)
 }
 
-// Test entity override - invalid value
-func TestGoHandler_Execute_EntityInvalidValue(t *testing.T) {
-	var validateCalled, executeCalled bool
-	clearEnvironment()
-	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-entity-override-invalid-value.json", nil,
-		func(event *types.Event) error {
-			validateCalled = true
-			assert.NotNil(t, event)
-			return nil
-		}, func(event *types.Event) error {
-			executeCalled = true
-			assert.NotNil(t, event)
-			return nil
-		},
-		"value-entity1", uint64(33333), false)
+func goHandlerExecuteUtil(t *testing.T, handlerConfig *HandlerConfig, eventFile string, cmdLineArgs []string,
+	validationFunction func(*types.Event) error, executeFunction func(*types.Event) error,
+	expectedValue1 interface{}, expectedValue2 interface{}, expectedValue3 interface{}) error {
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
 
-	assert.NotNil(t, err)
-	assert.False(t, validateCalled)
-	assert.False(t, executeCalled)
+	goHandler := NewGoHandler(handlerConfig, options, validationFunction, executeFunction)
+
+	if len(cmdLineArgs) > 0 {
+		goHandler.cmdArgs.SetArgs(cmdLineArgs)
+	} else {
+		goHandler.cmdArgs.SetArgs([]string{})
+	}
+
+	// Replace stdin reader with file reader
+	goHandler.eventReader = getFileReader(eventFile)
+	err := goHandler.Execute()
+
+	assert.Equal(t, expectedValue1, values.arg1)
+	assert.Equal(t, expectedValue2, values.arg2)
+	assert.Equal(t, expectedValue3, values.arg3)
+
+	return err
 }
 
 // Test environment
@@ -269,79 +326,36 @@ func TestGoHandler_Execute_Environment(t *testing.T) {
 	assert.True(t, executeCalled)
 }
 
-// Test cmd line arguments
-func TestGoHandler_Execute_CmdLineArgs(t *testing.T) {
-	var validateCalled, executeCalled bool
+// Test an environment value that does not parse is reported instead of being
+// replaced by the default value, unless the command line sets the option
+func TestGoHandler_Execute_EnvironmentInvalidValue(t *testing.T) {
 	clearEnvironment()
-	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-no-override.json", defaultCmdLineArgs,
+	_ = os.Setenv("ENV_2", "not-a-number")
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-no-override.json", nil,
 		func(event *types.Event) error {
-			validateCalled = true
-			assert.NotNil(t, event)
 			return nil
 		}, func(event *types.Event) error {
-			executeCalled = true
-			assert.NotNil(t, event)
 			return nil
 		},
-		"value-arg1", uint64(7531), false)
-	assert.Nil(t, err)
-	assert.True(t, validateCalled)
-	assert.True(t, executeCalled)
-}
+		"Default1", uint64(33333), false)
+	assert.EqualError(t, err, "invalid value in environment variable ENV_2: "+
+		"Error parsing not-a-number into a uint64 for option arg2")
 
-// Test check priority - check override
-func TestGoHandler_Execute_PriorityCheck(t *testing.T) {
-	var validateCalled, executeCalled bool
-	clearEnvironment()
-	_ = os.Setenv("ENV_1", "value-env1")
-	_ = os.Setenv("ENV_2", "9753")
-	_ = os.Setenv("ENV_3", "true")
-	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-check-entity-override.json", defaultCmdLineArgs,
+	err = goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-no-override.json", defaultCmdLineArgs,
 		func(event *types.Event) error {
-			validateCalled = true
-			assert.NotNil(t, event)
 			return nil
 		}, func(event *types.Event) error {
-			executeCalled = true
-			assert.NotNil(t, event)
 			return nil
 		},
-		"value-check1", uint64(1357), false)
+		"value-arg1", uint64(7531), false)
 	assert.Nil(t, err)
-	assert.True(t, validateCalled)
-	assert.True(t, executeCalled)
-}
-
-// Test next priority - entity override
-func TestGoHandler_Execute_PriorityEntity(t *testing.T) {
-	var validateCalled, executeCalled bool
 	clearEnvironment()
-	_ = os.Setenv("ENV_1", "value-env1")
-	_ = os.Setenv("ENV_2", "9753")
-	_ = os.Setenv("ENV_3", "true")
-	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-entity-override.json", defaultCmdLineArgs,
-		func(event *types.Event) error {
-			validateCalled = true
-			assert.NotNil(t, event)
-			return nil
-		}, func(event *types.Event) error {
-			executeCalled = true
-			assert.NotNil(t, event)
-			return nil
-		},
-		"value-entity1", uint64(2468), true)
-	assert.Nil(t, err)
-	assert.True(t, validateCalled)
-	assert.True(t, executeCalled)
 }
 
-// Test next priority - cmd line arguments
-func TestGoHandler_Execute_PriorityCmdLine(t *testing.T) {
+// Test cmd line arguments
+func TestGoHandler_Execute_CmdLineArgs(t *testing.T) {
 	var validateCalled, executeCalled bool
 	clearEnvironment()
-	_ = os.Setenv("ENV_1", "value-env1")
-	_ = os.Setenv("ENV_2", "9753")
-	_ = os.Setenv("ENV_3", "true")
 	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-no-override.json", defaultCmdLineArgs,
 		func(event *types.Event) error {
 			validateCalled = true
@@ -358,24 +372,50 @@ func TestGoHandler_Execute_PriorityCmdLine(t *testing.T) {
 	assert.True(t, executeCalled)
 }
 
-// Test validation error
-func TestGoHandler_Execute_ValidationError(t *testing.T) {
-	var validateCalled, executeCalled bool
+// Test check priority - check override
+// Test boolean options set by the bare flag, an explicit value or the
+// environment
+func TestGoHandler_Execute_BoolFlag(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		env      string
+		expected bool
+		source   string
+	}{
+		{"bare flag", []string{"--arg3"}, "", true, sourceCmdLine},
+		{"bare shorthand", []string{"-f"}, "", true, sourceCmdLine},
+		{"explicit false", []string{"--arg3=false"}, "true", false, sourceCmdLine},
+		{"environment", []string{}, "true", true, sourceEnv},
+		{"default", []string{}, "", false, sourceDefault},
+	}
+
+	for _, test := range tests {
+		clearEnvironment()
+		if len(test.env) > 0 {
+			_ = os.Setenv("ENV_3", test.env)
+		}
+		options := getDefaultOptions()
+		values := handlerValues{}
+		options[0].Value = &values.arg1
+		options[1].Value = &values.arg2
+		options[2].Value = &values.arg3
+
+		goHandler := NewGoHandler(&defaultHandlerConfig, options,
+			func(event *types.Event) error {
+				return nil
+			}, func(event *types.Event) error {
+				return nil
+			})
+		goHandler.cmdArgs.SetArgs(test.args)
+		goHandler.eventReader = getFileReader("test/event-no-override.json")
+		err := goHandler.Execute()
+
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.expected, values.arg3, test.name)
+		assert.Equal(t, test.source, options[2].source, test.name)
+	}
 	clearEnvironment()
-	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-no-override.json", defaultCmdLineArgs,
-		func(event *types.Event) error {
-			validateCalled = true
-			assert.NotNil(t, event)
-			return fmt.Errorf("validation error")
-		}, func(event *types.Event) error {
-			executeCalled = true
-			return nil
-		},
-		"value-arg1", uint64(7531), false)
-	assert.NotNil(t, err)
-	assert.EqualError(t, err, "error validating input: validation error")
-	assert.True(t, validateCalled)
-	assert.False(t, executeCalled)
 }
 
 // Test execute error
@@ -399,215 +439,282 @@ func TestGoHandler_Execute_ExecuteError(t *testing.T) {
 	assert.True(t, executeCalled)
 }
 
-// Test invalid event - no timestamp
-func TestGoHandler_Execute_EventNoTimestamp(t *testing.T) {
-	var validateCalled, executeCalled bool
+// Test the execute function is retried until it succeeds
+func TestGoHandler_Execute_Retry(t *testing.T) {
+	executeCount := 0
 	clearEnvironment()
-	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-no-timestamp.json", defaultCmdLineArgs,
+	handlerConfig := defaultHandlerConfig
+	handlerConfig.RetryCount = 3
+	handlerConfig.RetryBackoff = time.Millisecond
+	err := goHandlerExecuteUtil(t, &handlerConfig, "test/event-no-override.json", defaultCmdLineArgs,
 		func(event *types.Event) error {
-			validateCalled = true
-			assert.NotNil(t, event)
 			return nil
 		}, func(event *types.Event) error {
-			executeCalled = true
-			assert.NotNil(t, event)
+			executeCount++
+			if executeCount < 3 {
+				return fmt.Errorf("execution error %d", executeCount)
+			}
 			return nil
 		},
 		"value-arg1", uint64(7531), false)
-	assert.NotNil(t, err)
-	assert.EqualError(t, err, "timestamp is missing or must be greater than zero")
-	assert.False(t, validateCalled)
-	assert.False(t, executeCalled)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, executeCount)
 }
 
-// Test invalid event - timestamp 0
-func TestGoHandler_Execute_EventTimestampZero(t *testing.T) {
-	var validateCalled, executeCalled bool
+// Test the last error is returned once the retries are exhausted, and
+// validation errors are not retried
+func TestGoHandler_Execute_RetryExhausted(t *testing.T) {
+	var validateCount, executeCount int
 	clearEnvironment()
-	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-timestamp-zero.json", defaultCmdLineArgs,
+	handlerConfig := defaultHandlerConfig
+	handlerConfig.RetryCount = 2
+	handlerConfig.RetryBackoff = time.Millisecond
+	err := goHandlerExecuteUtil(t, &handlerConfig, "test/event-no-override.json", defaultCmdLineArgs,
 		func(event *types.Event) error {
-			validateCalled = true
-			assert.NotNil(t, event)
 			return nil
 		}, func(event *types.Event) error {
-			executeCalled = true
-			assert.NotNil(t, event)
-			return nil
+			executeCount++
+			return fmt.Errorf("execution error %d", executeCount)
 		},
 		"value-arg1", uint64(7531), false)
-	assert.NotNil(t, err)
-	assert.EqualError(t, err, "timestamp is missing or must be greater than zero")
-	assert.False(t, validateCalled)
-	assert.False(t, executeCalled)
-}
+	assert.EqualError(t, err, "error executing handler: execution error 3")
+	assert.Equal(t, 3, executeCount)
 
-// Test invalid event - no entity
-func TestGoHandler_Execute_EventNoEntity(t *testing.T) {
-	var validateCalled, executeCalled bool
-	clearEnvironment()
-	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-no-entity.json", defaultCmdLineArgs,
+	executeCount = 0
+	err = goHandlerExecuteUtil(t, &handlerConfig, "test/event-no-override.json", defaultCmdLineArgs,
 		func(event *types.Event) error {
-			validateCalled = true
-			assert.NotNil(t, event)
-			return nil
+			validateCount++
+			return fmt.Errorf("validation error")
 		}, func(event *types.Event) error {
-			executeCalled = true
-			assert.NotNil(t, event)
+			executeCount++
 			return nil
 		},
 		"value-arg1", uint64(7531), false)
-	assert.NotNil(t, err)
-	assert.EqualError(t, err, "entity is missing from event")
-	assert.False(t, validateCalled)
-	assert.False(t, executeCalled)
+	assert.EqualError(t, err, "error validating input: validation error")
+	assert.Equal(t, 1, validateCount)
+	assert.Equal(t, 0, executeCount)
+}
+
+func TestGoHandler_Execute_Cleanup(t *testing.T) {
+	tests := []struct {
+		validationErr error
+		executeErr    error
+		cleanupErr    error
+		expectedErr   string
+	}{
+		{nil, nil, nil, ""},
+		{fmt.Errorf("validation error"), nil, nil, "error validating input: validation error"},
+		{nil, fmt.Errorf("execute error"), nil, "error executing handler: execute error"},
+		{nil, nil, fmt.Errorf("close error"), "error cleaning up handler: close error"},
+		{nil, fmt.Errorf("execute error"), fmt.Errorf("close error"),
+			"error executing handler: execute error (error cleaning up handler: close error)"},
+	}
+
+	for _, test := range tests {
+		var cleanupCalled bool
+		clearEnvironment()
+		options := getDefaultOptions()
+		values := handlerValues{}
+		options[0].Value = &values.arg1
+		options[1].Value = &values.arg2
+		options[2].Value = &values.arg3
+		validationErr, executeErr, cleanupErr := test.validationErr, test.executeErr, test.cleanupErr
+
+		goHandler := NewGoHandler(&defaultHandlerConfig, options,
+			func(event *types.Event) error {
+				return validationErr
+			}, func(event *types.Event) error {
+				return executeErr
+			})
+		goHandler.SetCleanup(func() error {
+			cleanupCalled = true
+			return cleanupErr
+		})
+		goHandler.cmdArgs.SetArgs([]string{})
+		goHandler.eventReader = getFileReader("test/event-no-override.json")
+		err := goHandler.Execute()
+
+		assert.True(t, cleanupCalled)
+		if len(test.expectedErr) > 0 {
+			assert.EqualError(t, err, test.expectedErr)
+		} else {
+			assert.Nil(t, err)
+		}
+	}
 }
 
-// Test invalid event - invalid entity
-func TestGoHandler_Execute_EventInvalidEntity(t *testing.T) {
-	var validateCalled, executeCalled bool
+// Test panics in the validation and execute functions are returned as errors
+func TestGoHandler_Execute_Panic(t *testing.T) {
 	clearEnvironment()
-	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-invalid-entity.json", defaultCmdLineArgs,
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-no-override.json", []string{},
 		func(event *types.Event) error {
-			validateCalled = true
-			assert.NotNil(t, event)
 			return nil
 		}, func(event *types.Event) error {
-			executeCalled = true
-			assert.NotNil(t, event)
+			var annotations map[string]string
+			annotations["key"] = "value"
 			return nil
 		},
-		"value-arg1", uint64(7531), false)
-	assert.NotNil(t, err)
-	assert.EqualError(t, err, "entity name must not be empty")
-	assert.False(t, validateCalled)
-	assert.False(t, executeCalled)
-}
+		"Default1", uint64(33333), false)
+	assert.EqualError(t, err, "error executing handler: handler panicked: assignment to entry in nil map")
 
-// Test invalid event - no check
-func TestGoHandler_Execute_EventNoCheck(t *testing.T) {
-	var validateCalled, executeCalled bool
+	var executeCalled bool
 	clearEnvironment()
-	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-no-check.json", defaultCmdLineArgs,
+	err = goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-no-override.json", []string{},
 		func(event *types.Event) error {
-			validateCalled = true
-			assert.NotNil(t, event)
-			return nil
+			panic("invalid input")
 		}, func(event *types.Event) error {
 			executeCalled = true
-			assert.NotNil(t, event)
 			return nil
 		},
-		"value-arg1", uint64(7531), false)
-	assert.NotNil(t, err)
-	assert.EqualError(t, err, "check is missing from event")
-	assert.False(t, validateCalled)
+		"Default1", uint64(33333), false)
+	assert.EqualError(t, err, "error validating input: handler panicked: invalid input")
 	assert.False(t, executeCalled)
 }
 
-// Test invalid event - invalid check
-func TestGoHandler_Execute_EventInvalidCheck(t *testing.T) {
-	var validateCalled, executeCalled bool
-	clearEnvironment()
-	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-invalid-check.json", defaultCmdLineArgs,
-		func(event *types.Event) error {
-			validateCalled = true
-			assert.NotNil(t, event)
-			return nil
-		}, func(event *types.Event) error {
-			executeCalled = true
-			assert.NotNil(t, event)
-			return nil
-		},
-		"value-arg1", uint64(7531), false)
-	assert.NotNil(t, err)
-	assert.EqualError(t, err, "check name must not be empty")
-	assert.False(t, validateCalled)
-	assert.False(t, executeCalled)
+type fakeMetricsSink struct {
+	counters map[string]int
 }
 
-// Test unmarshalling error
-func TestGoHandler_Execute_EventInvalidJson(t *testing.T) {
-	var validateCalled, executeCalled bool
+func (sink *fakeMetricsSink) IncrCounter(name string, labels map[string]string) {
+	sink.counters[fmt.Sprintf("%s/%s/%s", name, labels["option"], labels["source"])]++
+}
+
+// Test option source metrics
+func TestGoHandler_Execute_MetricsSink(t *testing.T) {
 	clearEnvironment()
-	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-invalid-json.json", defaultCmdLineArgs,
+	_ = os.Setenv("ENV_2", "9753")
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+	sink := &fakeMetricsSink{counters: map[string]int{}}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
 		func(event *types.Event) error {
-			validateCalled = true
-			assert.NotNil(t, event)
 			return nil
 		}, func(event *types.Event) error {
-			executeCalled = true
-			assert.NotNil(t, event)
 			return nil
-		},
-		"value-arg1", uint64(7531), false)
-	assert.NotNil(t, err)
-	assert.EqualError(t, err, "Failed to unmarshal STDIN data: invalid character ':' after object key:value pair")
-	assert.False(t, validateCalled)
-	assert.False(t, executeCalled)
+		})
+	goHandler.SetMetricsSink(sink)
+	goHandler.cmdArgs.SetArgs([]string{"--arg3"})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+	clearEnvironment()
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{
+		"option_source/arg1/default": 1,
+		"option_source/arg2/env":     1,
+		"option_source/arg3/cmdline": 1,
+	}, sink.counters)
 }
 
-// Test fail to read stdin
-func TestGoHandler_Execute_ReaderError(t *testing.T) {
-	var validateCalled, executeCalled bool
+func TestGoHandler_OptionsAsEnv(t *testing.T) {
 	clearEnvironment()
-	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-invalid-json.json", defaultCmdLineArgs,
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+	options[2].Env = ""
+	options[2].Argument = "arg-3"
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
 		func(event *types.Event) error {
-			validateCalled = true
-			assert.NotNil(t, event)
 			return nil
 		}, func(event *types.Event) error {
-			executeCalled = true
-			assert.NotNil(t, event)
 			return nil
-		},
-		"value-arg1", uint64(7531), false)
-	assert.NotNil(t, err)
-	assert.EqualError(t, err, "Failed to unmarshal STDIN data: invalid character ':' after object key:value pair")
-	assert.False(t, validateCalled)
-	assert.False(t, executeCalled)
+		})
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-check-override.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ENV_1=value-check1", "ENV_2=1357", "ARG_3=false"}, goHandler.OptionsAsEnv())
 }
 
-// Test no keyspace
-func TestGoHandler_Execute_NoKeyspace(t *testing.T) {
-	var validateCalled, executeCalled bool
-	clearEnvironment()
+func TestWriteAnnotations(t *testing.T) {
+	options := getDefaultOptions()
+	options[1].Example = "12345"
+	options[2].Path = ""
+	var buffer bytes.Buffer
+
+	err := writeAnnotations(&buffer, &defaultHandlerConfig, options)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "annotations:\n"+
+		"  sensu.io/plugins/segp/config/path1: \"Default1\"\n"+
+		"  sensu.io/plugins/segp/config/path2: \"12345\"\n", buffer.String())
+}
+
+// Test the defaults are written the way the options parse them
+func TestWriteAnnotations_DefaultFormat(t *testing.T) {
+	var tags []string
+	var headers map[string]string
+	var rules routingRules
+	options := []*HandlerConfigOption{
+		{Path: "tags", Argument: "tags", Default: []string{"a", "b,c"}, Value: &tags},
+		{Path: "headers", Argument: "headers", Default: map[string]string{"X-Team": "ops"}, Value: &headers},
+		{Path: "rules", Argument: "rules", Default: routingRules{Default: "email"}, Value: &rules},
+	}
+	var buffer bytes.Buffer
+
+	err := writeAnnotations(&buffer, &defaultHandlerConfig, options)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "annotations:\n"+
+		`  sensu.io/plugins/segp/config/tags: "a,\"b,c\""`+"\n"+
+		`  sensu.io/plugins/segp/config/headers: "X-Team=ops"`+"\n"+
+		`  sensu.io/plugins/segp/config/rules: "{\"default\":\"email\",\"routes\":null}"`+"\n",
+		buffer.String())
+
+	// The examples parse back into the defaults
+	for i, value := range []string{`a,"b,c"`, "X-Team=ops", `{"default":"email","routes":null}`} {
+		assert.Nil(t, setOptionValue(options[i], value))
+	}
+	assert.Equal(t, []string{"a", "b,c"}, tags)
+	assert.Equal(t, map[string]string{"X-Team": "ops"}, headers)
+	assert.Equal(t, routingRules{Default: "email"}, rules)
+}
+
+func TestWriteAnnotations_OptionKeyspace(t *testing.T) {
 	handlerConfig := defaultHandlerConfig
 	handlerConfig.Keyspace = ""
-	err := goHandlerExecuteUtil(t, &handlerConfig, "test/event-check-entity-override.json", defaultCmdLineArgs,
-		func(event *types.Event) error {
-			validateCalled = true
-			assert.NotNil(t, event)
-			return nil
-		}, func(event *types.Event) error {
-			executeCalled = true
-			assert.NotNil(t, event)
-			return nil
-		},
-		"value-arg1", uint64(7531), false)
+	options := getDefaultOptions()
+	options[1].Keyspace = "example.com/org/config"
+	var buffer bytes.Buffer
+
+	err := writeAnnotations(&buffer, &handlerConfig, options)
+
 	assert.Nil(t, err)
-	assert.True(t, validateCalled)
-	assert.True(t, executeCalled)
+	assert.Equal(t, "annotations:\n"+
+		"  example.com/org/config/path2: \"33333\"\n", buffer.String())
 }
 
-func TestGoHandler_Execute_NoOptionValue(t *testing.T) {
+func TestWriteAnnotations_FullAnnotationKey(t *testing.T) {
 	options := getDefaultOptions()
-	handlerConfig := defaultHandlerConfig
+	options[0].FullAnnotationKey = "legacy_handler_arg1"
+	var buffer bytes.Buffer
 
-	goHandler := NewGoHandler(&handlerConfig, options,
-		func(event *types.Event) error {
-			return nil
-		}, func(event *types.Event) error {
-			return nil
-		})
+	err := writeAnnotations(&buffer, &defaultHandlerConfig, options)
 
-	goHandler.cmdArgs.SetArgs(defaultCmdLineArgs)
+	assert.Nil(t, err)
+	assert.Equal(t, "annotations:\n"+
+		"  legacy_handler_arg1: \"Default1\"\n"+
+		"  sensu.io/plugins/segp/config/path2: \"33333\"\n"+
+		"  sensu.io/plugins/segp/config/path3: \"false\"\n", buffer.String())
+}
 
-	// Replace stdin reader with file reader
-	goHandler.eventReader = getFileReader("test/event-check-entity-override.json")
-	err := goHandler.Execute()
+func TestWriteAnnotations_NoKeyspace(t *testing.T) {
+	handlerConfig := defaultHandlerConfig
+	handlerConfig.Keyspace = ""
+	var buffer bytes.Buffer
+
+	err := writeAnnotations(&buffer, &handlerConfig, getDefaultOptions())
 
 	assert.NotNil(t, err)
-	assert.Errorf(t, err, "Option value must not be nil for option arg1")
+	assert.Empty(t, buffer.String())
 }
 
 func getFileReader(file string) io.Reader {
@@ -627,3 +734,29 @@ func getDefaultOptions() []*HandlerConfigOption {
 	option3 := defaultOption3
 	return []*HandlerConfigOption{&option1, &option2, &option3}
 }
+
+// Test the combined errors of the execute functions keep their type
+func TestNewGoHandlerMulti_StatusError(t *testing.T) {
+	clearEnvironment()
+	exitStatus := -1
+	goHandler := NewGoHandlerMulti(&defaultHandlerConfig, []*HandlerConfigOption{},
+		func(event *types.Event) error {
+			return nil
+		}, []func(event *types.Event) error{
+			func(event *types.Event) error {
+				return fmt.Errorf("slack unavailable")
+			}, func(event *types.Event) error {
+				return &StatusError{Status: CheckStateWarning, Err: fmt.Errorf("queue is filling up")}
+			},
+		}, false, WithExitFunction(func(status int) {
+			exitStatus = status
+		}))
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.EqualError(t, err, "error executing handler: function 1: slack unavailable; function 2: queue is filling up")
+	var multiErr *MultiError
+	assert.True(t, errors.As(err, &multiErr))
+	assert.Equal(t, CheckStateWarning, exitStatus)
+}
@@ -0,0 +1,113 @@
+package sensu
+
+import (
+	"context"
+	"errors"
+	"github.com/sensu/sensu-enterprise-go-plugin/args"
+	"github.com/sensu/sensu-go/types"
+	"io"
+	"os"
+)
+
+// GoMetrics reads a Sensu event from stdin, resolves its options exactly like
+// GoHandler and passes the metric points carried by the event to its execute
+// function.
+type GoMetrics struct {
+	optionResolver
+	sensuEvent         *types.Event
+	validationFunction func(event *types.Event) error
+	executeFunction    func(points []*types.MetricPoint) error
+	eventReader        io.Reader
+	eventFile          string
+	inputFormat        string
+	showVersion        bool
+	outputWriter       io.Writer
+}
+
+// NewGoMetrics creates a GoMetrics with the given configuration, options,
+// validation function and execute function.
+func NewGoMetrics(config *HandlerConfig, options []*HandlerConfigOption,
+	validationFunction func(event *types.Event) error, executeFunction func(points []*types.MetricPoint) error) *GoMetrics {
+	goMetrics := &GoMetrics{
+		optionResolver:     newOptionResolver(config, options, os.Stderr),
+		sensuEvent:         nil,
+		validationFunction: validationFunction,
+		executeFunction:    executeFunction,
+		eventReader:        os.Stdin,
+		outputWriter:       os.Stdout,
+	}
+	cmdArgs := args.NewArgs(config.Name, config.Short, goMetrics.cobraExecute)
+	cmdArgs.AddCommand("annotations", "Print the keyspace annotations supported by this handler",
+		goMetrics.printAnnotations)
+	cmdArgs.PersistentStringVarP(&goMetrics.eventFile, "event-file", "", "",
+		"Read the event from this file instead of stdin")
+	cmdArgs.PersistentStringVarP(&goMetrics.inputFormat, "input-format", "", InputFormatJSON,
+		"Format of the event read from stdin or the event file (json or yaml)")
+	goMetrics.registerFlags(cmdArgs, true)
+
+	return goMetrics
+}
+
+// Execute parses the command line arguments and runs the metrics handler.
+func (goMetrics *GoMetrics) Execute() error {
+	// Setup arguments
+	err := goMetrics.registerOptions()
+	if err != nil {
+		return err
+	}
+	registerVersionFlag(goMetrics.cmdArgs, goMetrics.options, &goMetrics.showVersion)
+
+	// This will call cobraExecute so put the rest of the logic in there
+	return goMetrics.cmdArgs.Execute()
+}
+
+// SetOutputWriter sets the writer receiving the output written to stdout by
+// default, such as the annotations.
+func (goMetrics *GoMetrics) SetOutputWriter(writer io.Writer) {
+	goMetrics.outputWriter = writer
+}
+
+// printAnnotations prints the annotations subcommand output to stdout
+func (goMetrics *GoMetrics) printAnnotations(_ []string) error {
+	goMetrics.config = overrideKeyspace(goMetrics.config, goMetrics.keyspace)
+	return writeAnnotations(goMetrics.outputWriter, goMetrics.config, goMetrics.options)
+}
+
+func (goMetrics *GoMetrics) cobraExecute(_ []string) error {
+	if goMetrics.showVersion {
+		return writeVersion(goMetrics.outputWriter, goMetrics.config)
+	}
+
+	// Read Sensu event
+	reader, closeReader, err := eventFileReader(goMetrics.eventFile, goMetrics.eventReader)
+	if err != nil {
+		return err
+	}
+	defer closeReader()
+
+	sensuEvent, err := readEvent(reader, goMetrics.config, goMetrics.inputFormat)
+	if err != nil {
+		return withEventSource(err, goMetrics.eventFile)
+	}
+	goMetrics.sensuEvent = sensuEvent
+
+	if !sensuEvent.HasMetrics() || len(sensuEvent.Metrics.Points) == 0 {
+		return errors.New("event contains no metrics")
+	}
+
+	// Resolve the options and validate the input
+	err = goMetrics.resolve(goMetrics.sensuEvent, withContext(goMetrics.validationFunction))
+	if err != nil {
+		return err
+	}
+
+	// Forward the metric points using executeFunction
+	err = recoverHandler(context.Background(), goMetrics.sensuEvent, func(_ context.Context, event *types.Event) error {
+		return goMetrics.executeFunction(event.Metrics.Points)
+	})
+	if err != nil {
+		return &ExecutionError{Err: err}
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package sensu
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestNewGoMetrics(t *testing.T) {
+	options := getDefaultOptions()
+	goMetrics := NewGoMetrics(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(points []*types.MetricPoint) error {
+		return nil
+	})
+
+	assert.NotNil(t, goMetrics)
+	assert.Equal(t, options, goMetrics.options)
+	assert.Equal(t, &defaultHandlerConfig, goMetrics.config)
+	assert.NotNil(t, goMetrics.validationFunction)
+	assert.NotNil(t, goMetrics.executeFunction)
+	assert.Nil(t, goMetrics.sensuEvent)
+	assert.Equal(t, os.Stdin, goMetrics.eventReader)
+	assert.Equal(t, os.Stdout, goMetrics.outputWriter)
+	assert.NotNil(t, goMetrics.cmdArgs)
+}
+
+func goMetricsExecuteUtil(t *testing.T, eventFile string, executeFunction func([]*types.MetricPoint) error,
+	expectedValue1 interface{}, expectedValue2 interface{}, expectedValue3 interface{}) error {
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goMetrics := NewGoMetrics(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, executeFunction)
+	goMetrics.cmdArgs.SetArgs([]string{})
+
+	// Replace stdin reader with file reader
+	goMetrics.eventReader = getFileReader(eventFile)
+	err := goMetrics.Execute()
+
+	assert.Equal(t, expectedValue1, values.arg1)
+	assert.Equal(t, expectedValue2, values.arg2)
+	assert.Equal(t, expectedValue3, values.arg3)
+
+	return err
+}
+
+// Test the metric points are passed to the execute function
+func TestGoMetrics_Execute(t *testing.T) {
+	var receivedPoints []*types.MetricPoint
+	clearEnvironment()
+	err := goMetricsExecuteUtil(t, "test/event-metrics.json", func(points []*types.MetricPoint) error {
+		receivedPoints = points
+		return nil
+	}, "value-check1", uint64(1357), false)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(receivedPoints))
+	assert.Equal(t, "webserver01.cpu.user", receivedPoints[0].Name)
+	assert.Equal(t, 12.5, receivedPoints[0].Value)
+	assert.Equal(t, int64(1550816106), receivedPoints[0].Timestamp)
+	assert.Equal(t, "cpu0", receivedPoints[0].Tags[0].Value)
+	assert.Equal(t, "webserver01.load.load1", receivedPoints[2].Name)
+}
+
+// Test an event without metrics fails before the execute function is called
+func TestGoMetrics_Execute_NoMetrics(t *testing.T) {
+	var executeCalled bool
+	clearEnvironment()
+	err := goMetricsExecuteUtil(t, "test/event-check-override.json", func(points []*types.MetricPoint) error {
+		executeCalled = true
+		return nil
+	}, "Default1", uint64(33333), false)
+
+	assert.EqualError(t, err, "event contains no metrics")
+	assert.False(t, executeCalled)
+}
+
+// Test execute function error
+func TestGoMetrics_Execute_Error(t *testing.T) {
+	clearEnvironment()
+	err := goMetricsExecuteUtil(t, "test/event-metrics.json", func(points []*types.MetricPoint) error {
+		return fmt.Errorf("tsdb unavailable")
+	}, "value-check1", uint64(1357), false)
+
+	assert.EqualError(t, err, "error executing handler: tsdb unavailable")
+}
+
+func TestGoMetrics_SetOutputWriter(t *testing.T) {
+	var output bytes.Buffer
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goMetrics := NewGoMetrics(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(points []*types.MetricPoint) error {
+		return nil
+	})
+	goMetrics.SetOutputWriter(&output)
+	goMetrics.cmdArgs.SetArgs([]string{"annotations"})
+	err := goMetrics.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "annotations:\n"+
+		"  sensu.io/plugins/segp/config/path1: \"Default1\"\n"+
+		"  sensu.io/plugins/segp/config/path2: \"33333\"\n"+
+		"  sensu.io/plugins/segp/config/path3: \"false\"\n", output.String())
+}
+
+// Test the options are resolved like GoHandler's, from the --config file, and
+// a panicking validation function is reported as a validation error
+func TestGoMetrics_Execute_SharedResolution(t *testing.T) {
+	clearEnvironment()
+	configFile, err := ioutil.TempFile("", "config-*.yaml")
+	assert.Nil(t, err)
+	defer os.Remove(configFile.Name())
+	_, err = configFile.WriteString("arg4: value-config4\n")
+	assert.Nil(t, err)
+	assert.Nil(t, configFile.Close())
+
+	var arg4 string
+	options := []*HandlerConfigOption{{Argument: "arg4", Default: "", Value: &arg4}}
+	goMetrics := NewGoMetrics(&defaultHandlerConfig, options, func(event *types.Event) error {
+		panic("validation panic")
+	}, func(points []*types.MetricPoint) error {
+		return nil
+	})
+	goMetrics.cmdArgs.SetArgs([]string{"--config", configFile.Name()})
+	goMetrics.SetErrorWriter(ioutil.Discard)
+	goMetrics.eventReader = getFileReader("test/event-metrics.json")
+
+	err = goMetrics.Execute()
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.EqualError(t, err, "error validating input: handler panicked: validation panic")
+	assert.Equal(t, "value-config4", arg4)
+}
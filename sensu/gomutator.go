@@ -0,0 +1,162 @@
+package sensu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sensu/sensu-enterprise-go-plugin/args"
+	"github.com/sensu/sensu-go/types"
+	"io"
+	"log"
+	"os"
+)
+
+// GoMutator reads a Sensu event from stdin, resolves its options exactly like
+// GoHandler and writes the output of its mutate function to stdout.
+type GoMutator struct {
+	optionResolver
+	sensuEvent         *types.Event
+	validationFunction func(event *types.Event) error
+	mutateFunction     func(event *types.Event) ([]byte, error)
+	eventReader        io.Reader
+	eventFile          string
+	outputWriter       io.Writer
+	inputFormat        string
+	showVersion        bool
+}
+
+// NewGoMutator creates a GoMutator with the given configuration, options,
+// validation function and mutate function.
+func NewGoMutator(config *HandlerConfig, options []*HandlerConfigOption,
+	validationFunction func(event *types.Event) error, mutateFunction func(event *types.Event) ([]byte, error)) *GoMutator {
+	goMutator := &GoMutator{
+		optionResolver:     newOptionResolver(config, options, os.Stderr),
+		sensuEvent:         nil,
+		validationFunction: validationFunction,
+		mutateFunction:     mutateFunction,
+		eventReader:        os.Stdin,
+		outputWriter:       os.Stdout,
+	}
+	cmdArgs := args.NewArgs(config.Name, config.Short, goMutator.cobraExecute)
+	cmdArgs.AddCommand("annotations", "Print the keyspace annotations supported by this mutator",
+		goMutator.printAnnotations)
+	cmdArgs.PersistentStringVarP(&goMutator.eventFile, "event-file", "", "",
+		"Read the event from this file instead of stdin")
+	cmdArgs.PersistentStringVarP(&goMutator.inputFormat, "input-format", "", InputFormatJSON,
+		"Format of the event read from stdin or the event file (json or yaml)")
+	goMutator.registerFlags(cmdArgs, true)
+
+	return goMutator
+}
+
+// NewGoMutatorWithEvent creates a GoMutator whose execute function returns the
+// mutated event, which is written to stdout as JSON.
+func NewGoMutatorWithEvent(config *HandlerConfig, options []*HandlerConfigOption,
+	validationFunction func(event *types.Event) error,
+	executeFunction func(event *types.Event) (*types.Event, error)) *GoMutator {
+	return NewGoMutator(config, options, validationFunction, func(event *types.Event) ([]byte, error) {
+		mutatedEvent, err := executeFunction(event)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(mutatedEvent)
+	})
+}
+
+// Execute parses the command line arguments and runs the mutator.
+func (goMutator *GoMutator) Execute() error {
+	// Setup arguments
+	err := goMutator.registerOptions()
+	if err != nil {
+		return err
+	}
+	registerVersionFlag(goMutator.cmdArgs, goMutator.options, &goMutator.showVersion)
+
+	// This will call cobraExecute so put the rest of the logic in there
+	return goMutator.cmdArgs.Execute()
+}
+
+// SetOutputWriter sets the writer receiving the mutated event, stdout by
+// default.
+func (goMutator *GoMutator) SetOutputWriter(writer io.Writer) {
+	goMutator.outputWriter = writer
+}
+
+// printAnnotations prints the annotations subcommand output to stdout
+func (goMutator *GoMutator) printAnnotations(_ []string) error {
+	goMutator.config = overrideKeyspace(goMutator.config, goMutator.keyspace)
+	return writeAnnotations(goMutator.outputWriter, goMutator.config, goMutator.options)
+}
+
+func (goMutator *GoMutator) cobraExecute(_ []string) error {
+	if goMutator.showVersion {
+		return writeVersion(goMutator.outputWriter, goMutator.config)
+	}
+
+	// Read Sensu event
+	reader, closeReader, err := eventFileReader(goMutator.eventFile, goMutator.eventReader)
+	if err != nil {
+		return err
+	}
+	defer closeReader()
+
+	sensuEvent, err := readEvent(reader, goMutator.config, goMutator.inputFormat)
+	if err != nil {
+		return withEventSource(err, goMutator.eventFile)
+	}
+	goMutator.sensuEvent = sensuEvent
+
+	// Resolve the options and validate the input
+	err = goMutator.resolve(goMutator.sensuEvent, withContext(goMutator.validationFunction))
+	if err != nil {
+		return err
+	}
+
+	// Keep a copy of the event to compare it with the mutated event
+	var eventJSON []byte
+	if goMutator.config.DebugDiff {
+		eventJSON, err = json.Marshal(goMutator.sensuEvent)
+		if err != nil {
+			return fmt.Errorf("error marshalling event: %s", err)
+		}
+	}
+
+	// Mutate the event using mutateFunction and write the result
+	var output []byte
+	err = recoverHandler(context.Background(), goMutator.sensuEvent, func(_ context.Context, event *types.Event) error {
+		var mutateErr error
+		output, mutateErr = goMutator.mutateFunction(event)
+		return mutateErr
+	})
+	if err != nil {
+		return fmt.Errorf("error mutating event: %s", err)
+	}
+
+	if goMutator.config.DebugDiff {
+		logEventDiff(eventJSON, output, goMutator.errorLog())
+	}
+
+	_, err = goMutator.outputWriter.Write(output)
+	if err != nil {
+		return fmt.Errorf("error writing mutated event: %s", err)
+	}
+
+	return nil
+}
+
+// logEventDiff logs the JSON encoded differences between the event and the
+// mutator output
+func logEventDiff(eventJSON []byte, output []byte, errorLog *log.Logger) {
+	diff, err := EventDiff(eventJSON, output)
+	if err != nil {
+		errorLog.Printf("Unable to compare the mutated event: %s\n", err)
+		return
+	}
+
+	diffJSON, err := json.Marshal(diff)
+	if err != nil {
+		errorLog.Printf("Unable to marshal the mutated event diff: %s\n", err)
+		return
+	}
+	errorLog.Printf("Mutated event diff: %s\n", diffJSON)
+}
@@ -0,0 +1,227 @@
+package sensu
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestNewGoMutator(t *testing.T) {
+	options := getDefaultOptions()
+	goMutator := NewGoMutator(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) ([]byte, error) {
+		return nil, nil
+	})
+
+	assert.NotNil(t, goMutator)
+	assert.Equal(t, options, goMutator.options)
+	assert.Equal(t, &defaultHandlerConfig, goMutator.config)
+	assert.NotNil(t, goMutator.validationFunction)
+	assert.NotNil(t, goMutator.mutateFunction)
+	assert.Nil(t, goMutator.sensuEvent)
+	assert.Equal(t, os.Stdin, goMutator.eventReader)
+	assert.Equal(t, os.Stdout, goMutator.outputWriter)
+	assert.NotNil(t, goMutator.cmdArgs)
+}
+
+func goMutatorExecuteUtil(t *testing.T, eventFile string, mutateFunction func(*types.Event) ([]byte, error),
+	output *bytes.Buffer, expectedValue1 interface{}, expectedValue2 interface{}, expectedValue3 interface{}) error {
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goMutator := NewGoMutator(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, mutateFunction)
+	goMutator.cmdArgs.SetArgs([]string{})
+
+	// Replace stdin reader with file reader and stdout with a buffer
+	goMutator.eventReader = getFileReader(eventFile)
+	goMutator.outputWriter = output
+	err := goMutator.Execute()
+
+	assert.Equal(t, expectedValue1, values.arg1)
+	assert.Equal(t, expectedValue2, values.arg2)
+	assert.Equal(t, expectedValue3, values.arg3)
+
+	return err
+}
+
+// Test mutated output is written
+func TestGoMutator_Execute(t *testing.T) {
+	var output bytes.Buffer
+	clearEnvironment()
+	err := goMutatorExecuteUtil(t, "test/event-check-override.json", func(event *types.Event) ([]byte, error) {
+		return []byte(event.Check.Name), nil
+	}, &output, "value-check1", uint64(1357), false)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "check-nginx", output.String())
+}
+
+// Test mutate error
+func TestGoMutator_Execute_MutateError(t *testing.T) {
+	var output bytes.Buffer
+	clearEnvironment()
+	err := goMutatorExecuteUtil(t, "test/event-no-override.json", func(event *types.Event) ([]byte, error) {
+		return []byte("ignored"), fmt.Errorf("mutate error")
+	}, &output, "Default1", uint64(33333), false)
+
+	assert.EqualError(t, err, "error mutating event: mutate error")
+	assert.Empty(t, output.String())
+}
+
+// Test invalid event
+func TestGoMutator_Execute_InvalidEvent(t *testing.T) {
+	var output bytes.Buffer
+	mutateCalled := false
+	clearEnvironment()
+	err := goMutatorExecuteUtil(t, "test/event-no-entity.json", func(event *types.Event) ([]byte, error) {
+		mutateCalled = true
+		return nil, nil
+	}, &output, "Default1", uint64(33333), false)
+
+	assert.EqualError(t, err, "entity is missing from event")
+	assert.False(t, mutateCalled)
+}
+
+// Test the mutated event is written as JSON
+func TestGoMutatorWithEvent_Execute(t *testing.T) {
+	var output bytes.Buffer
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goMutator := NewGoMutatorWithEvent(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) (*types.Event, error) {
+		event.Check.Output = values.arg1
+		return event, nil
+	})
+	goMutator.cmdArgs.SetArgs([]string{})
+	goMutator.eventReader = getFileReader("test/event-check-override.json")
+	goMutator.outputWriter = &output
+	err := goMutator.Execute()
+
+	assert.Nil(t, err)
+	mutatedEvent := &types.Event{}
+	err = json.Unmarshal(output.Bytes(), mutatedEvent)
+	assert.Nil(t, err)
+	assert.Equal(t, "value-check1", mutatedEvent.Check.Output)
+	assert.Equal(t, "check-nginx", mutatedEvent.Check.Name)
+	assert.Equal(t, int64(1550816106), mutatedEvent.Timestamp)
+}
+
+// Test an error from the execute function is not written
+func TestGoMutatorWithEvent_Execute_Error(t *testing.T) {
+	var output bytes.Buffer
+	clearEnvironment()
+	err := goMutatorExecuteUtil(t, "test/event-no-override.json", NewGoMutatorWithEvent(&defaultHandlerConfig, nil, nil,
+		func(event *types.Event) (*types.Event, error) {
+			return nil, fmt.Errorf("mutate error")
+		}).mutateFunction, &output, "Default1", uint64(33333), false)
+
+	assert.EqualError(t, err, "error mutating event: mutate error")
+	assert.Empty(t, output.String())
+}
+
+// Test reading the event from a file
+func TestGoMutator_Execute_EventFile(t *testing.T) {
+	var output bytes.Buffer
+	clearEnvironment()
+	goMutator := NewGoMutator(&defaultHandlerConfig, []*HandlerConfigOption{}, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) ([]byte, error) {
+		return []byte(event.Entity.Name), nil
+	})
+	goMutator.cmdArgs.SetArgs([]string{"--event-file", "test/event-check-override.json"})
+	goMutator.eventReader = getFileReader("test/event-invalid-json.json")
+	goMutator.outputWriter = &output
+	err := goMutator.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "webserver01", output.String())
+}
+
+func TestGoMutator_SetOutputWriter(t *testing.T) {
+	var output bytes.Buffer
+	clearEnvironment()
+	goMutator := NewGoMutator(&defaultHandlerConfig, []*HandlerConfigOption{}, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) ([]byte, error) {
+		return []byte(event.Check.Name), nil
+	})
+	goMutator.SetOutputWriter(&output)
+	goMutator.cmdArgs.SetArgs([]string{})
+	goMutator.eventReader = getFileReader("test/event-check-override.json")
+	err := goMutator.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "check-nginx", output.String())
+}
+
+func TestGoMutator_SetErrorWriter(t *testing.T) {
+	var output, errorOutput bytes.Buffer
+	clearEnvironment()
+	handlerConfig := defaultHandlerConfig
+	handlerConfig.DebugDiff = true
+	goMutator := NewGoMutatorWithEvent(&handlerConfig, []*HandlerConfigOption{}, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) (*types.Event, error) {
+		event.Check.Output = "mutated"
+		return event, nil
+	})
+	goMutator.SetOutputWriter(&output)
+	goMutator.SetErrorWriter(&errorOutput)
+	goMutator.cmdArgs.SetArgs([]string{})
+	goMutator.eventReader = getFileReader("test/event-check-override.json")
+	err := goMutator.Execute()
+
+	assert.Nil(t, err)
+	assert.Contains(t, errorOutput.String(), "Mutated event diff:")
+	assert.NotContains(t, output.String(), "Mutated event diff:")
+}
+
+// Test the options are resolved like GoHandler's, from the --config file and
+// the sources added with ResolveFrom, and panics are recovered
+func TestGoMutator_Execute_SharedResolution(t *testing.T) {
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	var output bytes.Buffer
+	goMutator := NewGoMutator(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) ([]byte, error) {
+		return []byte("mutated"), nil
+	})
+	goMutator.cmdArgs.SetArgs([]string{"--config", "test/config.yaml"})
+	goMutator.ResolveFrom(map[string]string{"path2": "9753"}, "backend", PrecedenceAboveCheck)
+	goMutator.eventReader = getFileReader("test/event-no-override.json")
+	goMutator.outputWriter = &output
+
+	err := goMutator.Execute()
+	assert.Nil(t, err)
+	assert.Equal(t, "value-config1", values.arg1)
+	assert.Equal(t, uint64(9753), values.arg2)
+	assert.Equal(t, true, values.arg3)
+	assert.Equal(t, "mutated", output.String())
+
+	err = goMutatorExecuteUtil(t, "test/event-no-override.json", func(event *types.Event) ([]byte, error) {
+		panic("mutate panic")
+	}, &bytes.Buffer{}, "Default1", uint64(33333), false)
+	assert.EqualError(t, err, "error mutating event: handler panicked: mutate panic")
+}
@@ -0,0 +1,88 @@
+package sensu
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// LogLevel is the verbosity of the logs emitted while executing a handler
+type LogLevel string
+
+// Log levels accepted by the --log-level flag, from the least to the most
+// verbose. Logging is disabled when no level is set.
+const (
+	LogLevelError LogLevel = "error"
+	LogLevelInfo  LogLevel = "info"
+	LogLevelDebug LogLevel = "debug"
+)
+
+var logLevels = []LogLevel{LogLevelError, LogLevelInfo, LogLevelDebug}
+
+// Logger receives the structured logs emitted while executing a handler
+type Logger interface {
+	Log(level LogLevel, message string, fields map[string]interface{})
+}
+
+type jsonLogger struct {
+	writer io.Writer
+	mutex  sync.Mutex
+}
+
+// NewJSONLogger returns a Logger writing every log entry to writer as a JSON
+// object on its own line.
+func NewJSONLogger(writer io.Writer) Logger {
+	return &jsonLogger{writer: writer}
+}
+
+func (logger *jsonLogger) Log(level LogLevel, message string, fields map[string]interface{}) {
+	entry := make(map[string]interface{}, len(fields)+3)
+	for key, value := range fields {
+		entry[key] = value
+	}
+	entry["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	entry["level"] = level
+	entry["message"] = message
+
+	entryJSON, err := json.Marshal(entry)
+	if err != nil {
+		entryJSON, _ = json.Marshal(map[string]interface{}{
+			"time":    entry["time"],
+			"level":   level,
+			"message": message,
+			"error":   fmt.Sprintf("unable to marshal log fields: %s", err),
+		})
+	}
+
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+	_, _ = logger.writer.Write(append(entryJSON, '\n'))
+}
+
+// checkLogLevel returns an error if level is neither empty nor a known level
+func checkLogLevel(level LogLevel) error {
+	if level == "" || logLevelIndex(level) >= 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid log level %q, must be one of %v", level, logLevels)
+}
+
+// logLevelEnabled returns true if entries of the given level are logged when
+// the configured level is set
+func logLevelEnabled(configured LogLevel, level LogLevel) bool {
+	if configured == "" {
+		return false
+	}
+	return logLevelIndex(level) <= logLevelIndex(configured)
+}
+
+func logLevelIndex(level LogLevel) int {
+	for i, logLevel := range logLevels {
+		if logLevel == level {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,187 @@
+package sensu
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+type logEntry map[string]interface{}
+
+func readLogEntries(t *testing.T, buffer *bytes.Buffer) []logEntry {
+	entries := []logEntry{}
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		if len(line) == 0 {
+			continue
+		}
+		entry := logEntry{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func executeWithLogLevel(t *testing.T, cmdArgs []string, validationErr error) ([]logEntry, error) {
+	var buffer bytes.Buffer
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return validationErr
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.SetLogger(NewJSONLogger(&buffer))
+	goHandler.cmdArgs.SetArgs(cmdArgs)
+	goHandler.eventReader = getFileReader("test/event-check-override.json")
+	err := goHandler.Execute()
+
+	return readLogEntries(t, &buffer), err
+}
+
+func TestJSONLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := NewJSONLogger(&buffer)
+	logger.Log(LogLevelInfo, "message1", map[string]interface{}{"key": "value"})
+	logger.Log(LogLevelDebug, "message2", nil)
+
+	entries := readLogEntries(t, &buffer)
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, "info", entries[0]["level"])
+	assert.Equal(t, "message1", entries[0]["message"])
+	assert.Equal(t, "value", entries[0]["key"])
+	assert.NotEmpty(t, entries[0]["time"])
+	assert.Equal(t, "debug", entries[1]["level"])
+	assert.Equal(t, "message2", entries[1]["message"])
+}
+
+func TestLogLevelEnabled(t *testing.T) {
+	assert.False(t, logLevelEnabled("", LogLevelError))
+	assert.True(t, logLevelEnabled(LogLevelError, LogLevelError))
+	assert.False(t, logLevelEnabled(LogLevelError, LogLevelInfo))
+	assert.True(t, logLevelEnabled(LogLevelInfo, LogLevelError))
+	assert.True(t, logLevelEnabled(LogLevelInfo, LogLevelInfo))
+	assert.False(t, logLevelEnabled(LogLevelInfo, LogLevelDebug))
+	assert.True(t, logLevelEnabled(LogLevelDebug, LogLevelDebug))
+}
+
+// Test logging is off by default
+func TestGoHandler_Execute_NoLogLevel(t *testing.T) {
+	entries, err := executeWithLogLevel(t, []string{}, nil)
+
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestGoHandler_Execute_LogLevelInfo(t *testing.T) {
+	entries, err := executeWithLogLevel(t, []string{"--log-level", "info"}, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, "handler started", entries[0]["message"])
+	assert.Equal(t, "handler finished", entries[1]["message"])
+	assert.NotEmpty(t, entries[1]["duration"])
+}
+
+func TestGoHandler_Execute_LogLevelDebug(t *testing.T) {
+	entries, err := executeWithLogLevel(t, []string{"--log-level", "debug", "--arg2", "5"}, nil)
+
+	assert.Nil(t, err)
+	messages := []string{}
+	for _, entry := range entries {
+		messages = append(messages, entry["message"].(string))
+	}
+	assert.Equal(t, []string{"handler started", "event read", "option resolved", "option resolved",
+		"option resolved", "input validated", "handler finished"}, messages)
+	assert.Equal(t, "webserver01/check-nginx", entries[1]["event"])
+	assert.Equal(t, "arg1", entries[2]["option"])
+	assert.Equal(t, sourceCheck, entries[2]["source"])
+	assert.Equal(t, "value-check1", entries[2]["value"])
+	assert.Equal(t, "arg3", entries[4]["option"])
+	assert.Equal(t, sourceCheck, entries[4]["source"])
+}
+
+func TestGoHandler_Execute_LogLevelError(t *testing.T) {
+	entries, err := executeWithLogLevel(t, []string{"--log-level", "error"}, fmt.Errorf("validation error"))
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, "input validation failed", entries[0]["message"])
+	assert.Equal(t, "validation error", entries[0]["error"])
+	assert.Equal(t, "handler failed", entries[1]["message"])
+	assert.Equal(t, "error", entries[1]["level"])
+}
+
+func TestGoHandler_Execute_InvalidLogLevel(t *testing.T) {
+	entries, err := executeWithLogLevel(t, []string{"--log-level", "verbose"}, nil)
+
+	assert.EqualError(t, err, `invalid log level "verbose", must be one of [error info debug]`)
+	assert.Empty(t, entries)
+}
+
+type capturingLogger struct {
+	levels   []LogLevel
+	messages []string
+	sources  map[string]interface{}
+}
+
+func (logger *capturingLogger) Log(level LogLevel, message string, fields map[string]interface{}) {
+	logger.levels = append(logger.levels, level)
+	logger.messages = append(logger.messages, message)
+	if message == "option resolved" {
+		logger.sources[fields["option"].(string)] = fields["source"]
+	}
+}
+
+func TestGoHandler_SetLogger(t *testing.T) {
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+	logger := &capturingLogger{sources: map[string]interface{}{}}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.SetLogger(logger)
+	goHandler.cmdArgs.SetArgs([]string{"--log-level", "debug", "--arg2", "5"})
+	goHandler.eventReader = getFileReader("test/event-check-override.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "handler started", logger.messages[0])
+	assert.Equal(t, LogLevelInfo, logger.levels[0])
+	assert.Equal(t, "handler finished", logger.messages[len(logger.messages)-1])
+	assert.Equal(t, map[string]interface{}{"arg1": sourceCheck, "arg2": sourceCheck, "arg3": sourceCheck},
+		logger.sources)
+
+	logger = &capturingLogger{sources: map[string]interface{}{}}
+	goHandler = NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.SetLogger(logger)
+	goHandler.cmdArgs.SetArgs([]string{"--log-level", "debug", "--arg2", "5"})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err = goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"arg1": sourceDefault, "arg2": sourceCmdLine, "arg3": sourceDefault},
+		logger.sources)
+}
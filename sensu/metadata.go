@@ -0,0 +1,97 @@
+package sensu
+
+import (
+	"reflect"
+)
+
+// Event types a plugin can process, as listed in its Metadata
+const (
+	EventTypeCheck   = "check"
+	EventTypeMetrics = "metrics"
+)
+
+// Metadata describes a plugin and its options in a form suitable for
+// generating asset catalog entries.
+type Metadata struct {
+	Name       string           `json:"name"`
+	Short      string           `json:"short"`
+	Version    string           `json:"version,omitempty"`
+	Keyspace   string           `json:"keyspace,omitempty"`
+	EventTypes []string         `json:"event_types"`
+	Options    []OptionMetadata `json:"options"`
+}
+
+// OptionMetadata describes a plugin option
+type OptionMetadata struct {
+	Argument      string      `json:"argument"`
+	Shorthand     string      `json:"shorthand,omitempty"`
+	Env           string      `json:"env,omitempty"`
+	Path          string      `json:"path,omitempty"`
+	Keyspace      string      `json:"keyspace,omitempty"`
+	AnnotationKey string      `json:"annotation_key,omitempty"`
+	Type          string      `json:"type"`
+	Default       interface{} `json:"default,omitempty"`
+	Usage         string      `json:"usage"`
+	Required      bool        `json:"required,omitempty"`
+	AllowedValues []string    `json:"allowed_values,omitempty"`
+	Secret        bool        `json:"secret,omitempty"`
+}
+
+// Metadata returns the description of the handler and its options
+func (goHandler *GoHandler) Metadata() Metadata {
+	return pluginMetadata(goHandler.config, goHandler.options, []string{EventTypeCheck, EventTypeMetrics})
+}
+
+// Metadata returns the description of the mutator and its options
+func (goMutator *GoMutator) Metadata() Metadata {
+	return pluginMetadata(goMutator.config, goMutator.options, []string{EventTypeCheck, EventTypeMetrics})
+}
+
+// Metadata returns the description of the metrics handler and its options
+func (goMetrics *GoMetrics) Metadata() Metadata {
+	return pluginMetadata(goMetrics.config, goMetrics.options, []string{EventTypeMetrics})
+}
+
+func pluginMetadata(config *HandlerConfig, options []*HandlerConfigOption, eventTypes []string) Metadata {
+	metadata := Metadata{
+		Name:       config.Name,
+		Short:      config.Short,
+		Version:    config.Version,
+		Keyspace:   config.Keyspace,
+		EventTypes: eventTypes,
+		Options:    make([]OptionMetadata, 0, len(options)),
+	}
+
+	for _, opt := range options {
+		metadata.Options = append(metadata.Options, OptionMetadata{
+			Argument:      opt.Argument,
+			Shorthand:     opt.Shorthand,
+			Env:           opt.Env,
+			Path:          opt.Path,
+			Keyspace:      opt.Keyspace,
+			AnnotationKey: opt.FullAnnotationKey,
+			Type:          optionType(opt),
+			Default:       displayValue(opt, opt.Default),
+			Usage:         opt.Usage,
+			Required:      opt.Required,
+			AllowedValues: opt.AllowedValues,
+			Secret:        opt.Secret,
+		})
+	}
+	return metadata
+}
+
+// optionType returns the name of the type of the option value, options using
+// Parse are read as strings
+func optionType(option *HandlerConfigOption) string {
+	if option.Parse != nil || option.Value == nil {
+		return "string"
+	}
+	if isJSONOption(option) {
+		return "json"
+	}
+	if isBytesOption(option) {
+		return "bytes"
+	}
+	return reflect.Indirect(reflect.ValueOf(option.Value)).Type().String()
+}
@@ -0,0 +1,63 @@
+package sensu
+
+import (
+	"encoding/json"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestGoHandler_Metadata(t *testing.T) {
+	handlerConfig := defaultHandlerConfig
+	handlerConfig.Version = "1.2.3"
+	options := getDefaultOptions()
+	values := handlerValues{}
+	var timeout time.Duration
+	var rules routingRules
+	options[0].Value = &values.arg1
+	options[0].Secret = true
+	options[1].Value = &values.arg2
+	options[1].Required = true
+	options[2].Value = &values.arg3
+	options = append(options,
+		&HandlerConfigOption{Argument: "timeout", Default: time.Second, Usage: "Timeout", Value: &timeout},
+		&HandlerConfigOption{Argument: "rules", Usage: "Routing rules", Value: &rules})
+
+	goHandler := NewGoHandler(&handlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) error {
+		return nil
+	})
+	metadata := goHandler.Metadata()
+
+	assert.Equal(t, "TestHandler", metadata.Name)
+	assert.Equal(t, "1.2.3", metadata.Version)
+	assert.Equal(t, []string{EventTypeCheck, EventTypeMetrics}, metadata.EventTypes)
+	arguments := []string{}
+	for _, option := range metadata.Options {
+		arguments = append(arguments, option.Argument)
+	}
+	assert.Equal(t, []string{"arg1", "arg2", "arg3", "timeout", "rules"}, arguments)
+	assert.Equal(t, "string", metadata.Options[0].Type)
+	assert.Equal(t, "****", metadata.Options[0].Default)
+	assert.Equal(t, "uint64", metadata.Options[1].Type)
+	assert.True(t, metadata.Options[1].Required)
+	assert.Equal(t, "bool", metadata.Options[2].Type)
+	assert.Equal(t, "time.Duration", metadata.Options[3].Type)
+	assert.Equal(t, "json", metadata.Options[4].Type)
+
+	metadataJSON, err := json.Marshal(metadata)
+	assert.Nil(t, err)
+	assert.Contains(t, string(metadataJSON), `"name":"TestHandler"`)
+	assert.Contains(t, string(metadataJSON), `"argument":"arg2","shorthand":"e","env":"ENV_2","path":"path2","type":"uint64"`)
+}
+
+func TestGoMetrics_Metadata(t *testing.T) {
+	goMetrics := NewGoMetrics(&defaultHandlerConfig, getDefaultOptions(), nil, nil)
+	metadata := goMetrics.Metadata()
+
+	assert.Equal(t, "TestHandler", metadata.Name)
+	assert.Equal(t, []string{EventTypeMetrics}, metadata.EventTypes)
+	assert.Equal(t, 3, len(metadata.Options))
+}
@@ -0,0 +1,761 @@
+package sensu
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/sensu/sensu-enterprise-go-plugin/args"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setupOptions registers the handler options as command line arguments, once
+func (goHandler *GoHandler) setupOptions() error {
+	if goHandler.optionsRegistered {
+		return nil
+	}
+
+	err := goHandler.registerOptions()
+	if err != nil {
+		return err
+	}
+	registerVersionFlag(goHandler.cmdArgs, goHandler.options, &goHandler.showVersion)
+	goHandler.optionsRegistered = true
+
+	return nil
+}
+
+// reservedArguments are the names of the built-in flags, which options cannot
+// use as their Argument
+var reservedArguments = []string{
+	"batch", "config", "dry-run", "event-file", "help", "input-format", "keyspace", "log-level", "output",
+	"version",
+}
+
+// checkDuplicateOptions returns an error if two options share the same
+// Argument or non-empty Shorthand, which the command line cannot tell apart,
+// or if an option uses the Argument of a built-in flag
+func checkDuplicateOptions(options []*HandlerConfigOption) error {
+	arguments := make(map[string]int, len(options))
+	shorthands := make(map[string]*HandlerConfigOption, len(options))
+	for i, option := range options {
+		for _, reserved := range reservedArguments {
+			if option.Argument == reserved {
+				return fmt.Errorf("argument '%s' of option %d is reserved for the built-in --%s flag",
+					option.Argument, i+1, reserved)
+			}
+		}
+
+		if previous, ok := arguments[option.Argument]; ok {
+			return fmt.Errorf("duplicate argument '%s' for options %d and %d", option.Argument, previous+1, i+1)
+		}
+		arguments[option.Argument] = i
+
+		if len(option.Shorthand) == 0 {
+			continue
+		}
+		if previous, ok := shorthands[option.Shorthand]; ok {
+			return fmt.Errorf("duplicate shorthand '%s' for options %s and %s",
+				option.Shorthand, previous.Argument, option.Argument)
+		}
+		shorthands[option.Shorthand] = option
+	}
+	return nil
+}
+
+// compilePattern compiles the Pattern of the option, returning an error if it
+// is not a valid regular expression
+func compilePattern(option *HandlerConfigOption) error {
+	option.pattern = nil
+	if len(option.Pattern) == 0 {
+		return nil
+	}
+
+	pattern, err := regexp.Compile(option.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q for option %s: %s", option.Pattern, option.Argument, err)
+	}
+	option.pattern = pattern
+	return nil
+}
+
+// checkDefaultType returns an error if the option's Default is not of the type
+// its Value points to. A Required option may leave its Default nil.
+func checkDefaultType(option *HandlerConfigOption) error {
+	if option.Default == nil && option.Required {
+		return nil
+	}
+
+	valueType := reflect.TypeOf(option.Value)
+	if valueType.Kind() != reflect.Ptr || reflect.TypeOf(option.Default) != valueType.Elem() {
+		return fmt.Errorf("option %s: default type %T incompatible with value type %T",
+			option.Argument, option.Default, option.Value)
+	}
+	return nil
+}
+
+// applyDefault sets the option value to its Default, formatted and parsed like
+// the values read from the command line, the environment or the event. The
+// value is left unchanged when the Default is nil.
+func applyDefault(option *HandlerConfigOption) error {
+	if option.Default == nil {
+		return nil
+	}
+	return setOptionValue(option, formatDefault(option.Default))
+}
+
+// formatDefault formats a default value the way setOptionValue parses it
+func formatDefault(value interface{}) string {
+	sliceValue, ok := value.([]string)
+	if !ok {
+		return fmt.Sprint(value)
+	}
+
+	var builder strings.Builder
+	writer := csv.NewWriter(&builder)
+	_ = writer.Write(sliceValue)
+	writer.Flush()
+	return strings.TrimSuffix(builder.String(), "\n")
+}
+
+// defaultString formats the Default of an option the way it is parsed back
+// from the command line, the environment or an annotation
+func defaultString(option *HandlerConfigOption) string {
+	if isRawOption(option) {
+		return rawDefault(option)
+	}
+	return formatDefault(option.Default)
+}
+
+// resolveEnv returns the environment variable an option is read from: its Env
+// unless it is unset or empty and one of its EnvAlternatives has a value. With
+// caseInsensitive the names are matched ignoring case when the exact name is
+// not set.
+func resolveEnv(option *HandlerConfigOption, caseInsensitive bool, errorLog *log.Logger) string {
+	optionEnv := envName(option.Env, caseInsensitive)
+	if len(os.Getenv(optionEnv)) > 0 || len(option.EnvAlternatives) == 0 {
+		return optionEnv
+	}
+
+	for _, env := range option.EnvAlternatives {
+		env = envName(env, caseInsensitive)
+		if len(os.Getenv(env)) > 0 {
+			errorLog.Printf("Reading option %s from environment variable %s\n", option.Argument, env)
+			return env
+		}
+	}
+	return optionEnv
+}
+
+// envName returns name, or with caseInsensitive when name is not set, the
+// name of the first environment variable equal to name ignoring case
+func envName(name string, caseInsensitive bool) string {
+	if _, ok := os.LookupEnv(name); ok || !caseInsensitive || len(name) == 0 {
+		return name
+	}
+
+	for _, entry := range os.Environ() {
+		key := strings.SplitN(entry, "=", 2)[0]
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+	return name
+}
+
+// MaxOptions is the maximum number of options a plugin can define, guarding
+// against generated option sets gone wrong
+var MaxOptions = 256
+
+// CheckOptions returns an error if the options are not defined correctly: more
+// than MaxOptions options, duplicate or reserved arguments, an invalid Pattern,
+// a nil Value or a Default of another type than the Value. The plugins report
+// these errors when Execute is called, CheckOptions lets a plugin test them
+// without reading an event.
+func CheckOptions(options []*HandlerConfigOption) error {
+	if len(options) > MaxOptions {
+		return fmt.Errorf("%d options defined, the maximum is %d", len(options), MaxOptions)
+	}
+
+	if err := checkDuplicateOptions(options); err != nil {
+		return err
+	}
+
+	for _, option := range options {
+		if err := compilePattern(option); err != nil {
+			return err
+		}
+
+		if isRawOption(option) {
+			if err := checkRawDefault(option); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if option.Value == nil {
+			return fmt.Errorf("Option value must not be nil for option %s", option.Argument)
+		}
+
+		if err := checkDefaultType(option); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyRawDefault sets the value of a raw option to its Default, so that it is
+// available before the options are resolved like the other defaults. The
+// options with a custom parser are only given their Default once resolved.
+func applyRawDefault(option *HandlerConfigOption) error {
+	if option.Parse != nil {
+		return nil
+	}
+
+	rawValue := rawDefault(option)
+	if len(rawValue) == 0 {
+		return nil
+	}
+	return setOptionValue(option, rawValue)
+}
+
+// checkRawDefault returns an error if the Default of a raw option is neither a
+// string nor of the type its Value points to, or does not parse into the option
+// type. The Default of an option with a custom parser is not checked, an empty
+// Default leaves the option unset.
+func checkRawDefault(option *HandlerConfigOption) error {
+	if option.Parse != nil || option.Default == nil {
+		return nil
+	}
+
+	if _, ok := option.Default.(string); !ok {
+		if err := checkDefaultType(option); err != nil {
+			return err
+		}
+	}
+
+	// An empty raw value leaves the option unset, like parseCustomOptions
+	rawValue := rawDefault(option)
+	if len(rawValue) == 0 {
+		return nil
+	}
+
+	parsedOption := *option
+	parsedOption.Value = reflect.New(reflect.TypeOf(option.Value).Elem()).Interface()
+	if err := setOptionValue(&parsedOption, rawValue); err != nil {
+		return fmt.Errorf("option %s: invalid default: %s", option.Argument, err)
+	}
+	return nil
+}
+
+// setupOptions registers every option as a command line argument
+func setupOptions(cmdArgs *args.Args, options []*HandlerConfigOption, caseInsensitiveEnv bool,
+	errorLog *log.Logger) error {
+	if err := CheckOptions(options); err != nil {
+		return err
+	}
+
+	for _, option := range options {
+		option.env = resolveEnv(option, caseInsensitiveEnv, errorLog)
+
+		if isRawOption(option) {
+			cmdArgs.StringVarP(&option.rawValue, option.Argument, option.Shorthand, option.env,
+				rawDefault(option), option.Usage)
+			if err := applyRawDefault(option); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := applyDefault(option); err != nil {
+			return err
+		}
+
+		switch (option.Value).(type) {
+		case *string:
+			valuePtr, _ := option.Value.(*string)
+			cmdArgs.StringVarP(valuePtr, option.Argument, option.Shorthand, option.env,
+				*valuePtr, option.Usage)
+		case *uint64:
+			valuePtr, _ := option.Value.(*uint64)
+			cmdArgs.Uint64VarP(valuePtr, option.Argument, option.Shorthand, option.env,
+				*valuePtr, option.Usage)
+		case *uint32:
+			valuePtr, _ := option.Value.(*uint32)
+			cmdArgs.Uint32VarP(valuePtr, option.Argument, option.Shorthand, option.env,
+				*valuePtr, option.Usage)
+		case *int:
+			valuePtr, _ := option.Value.(*int)
+			cmdArgs.IntVarP(valuePtr, option.Argument, option.Shorthand, option.env,
+				*valuePtr, option.Usage)
+		case *int32:
+			valuePtr, _ := option.Value.(*int32)
+			cmdArgs.Int32VarP(valuePtr, option.Argument, option.Shorthand, option.env,
+				*valuePtr, option.Usage)
+		case *int64:
+			valuePtr, _ := option.Value.(*int64)
+			cmdArgs.Int64VarP(valuePtr, option.Argument, option.Shorthand, option.env,
+				*valuePtr, option.Usage)
+		case *float64:
+			valuePtr, _ := option.Value.(*float64)
+			cmdArgs.Float64VarP(valuePtr, option.Argument, option.Shorthand, option.env,
+				*valuePtr, option.Usage)
+		case *float32:
+			valuePtr, _ := option.Value.(*float32)
+			cmdArgs.Float32VarP(valuePtr, option.Argument, option.Shorthand, option.env,
+				*valuePtr, option.Usage)
+		case *time.Duration:
+			valuePtr, _ := option.Value.(*time.Duration)
+			cmdArgs.DurationVarP(valuePtr, option.Argument, option.Shorthand, option.env,
+				*valuePtr, option.Usage)
+		case *[]string:
+			valuePtr, _ := option.Value.(*[]string)
+			cmdArgs.StringSliceVarP(valuePtr, option.Argument, option.Shorthand, option.env,
+				*valuePtr, option.Usage)
+		case *bool:
+			valuePtr, _ := option.Value.(*bool)
+			cmdArgs.BoolVarP(valuePtr, option.Argument, option.Shorthand, option.env,
+				*valuePtr, option.Usage)
+		}
+	}
+
+	return nil
+}
+
+// parseCustomOptions passes the value resolved from the command line or the
+// environment to the Parse function of the options defining one
+func parseCustomOptions(options []*HandlerConfigOption) error {
+	for _, opt := range options {
+		if isRawOption(opt) && len(opt.rawValue) > 0 {
+			if err := setOptionValue(opt, opt.rawValue); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveSources records whether each option was set on the command line, in
+// the environment or left to its default value. An error is returned if the
+// value of an environment variable does not parse, instead of silently falling
+// back to the default value.
+func resolveSources(cmdArgs *args.Args, options []*HandlerConfigOption) error {
+	for _, opt := range options {
+		envValue, envSet := os.LookupEnv(opt.env)
+		switch {
+		case cmdArgs.Changed(opt.Argument):
+			opt.source = sourceCmdLine
+		case len(opt.env) > 0 && envSet:
+			if err := checkEnvValue(opt, envValue); err != nil {
+				opt.source = sourceDefault
+				return err
+			}
+			opt.source = sourceEnv
+		default:
+			opt.source = sourceDefault
+		}
+	}
+	return nil
+}
+
+// checkEnvValue returns an error if the value of the environment variable of
+// the option does not parse into the option type. The values of the options
+// with a custom parser are checked by parseCustomOptions.
+func checkEnvValue(option *HandlerConfigOption, envValue string) error {
+	if isRawOption(option) || option.Value == nil {
+		return nil
+	}
+
+	parsedOption := *option
+	parsedOption.Value = reflect.New(reflect.TypeOf(option.Value).Elem()).Interface()
+	if err := setOptionValue(&parsedOption, envValue); err != nil {
+		return fmt.Errorf("invalid value in environment variable %s: %s", option.env, err)
+	}
+	return nil
+}
+
+// requireOptions returns an error if a required option was not set by any
+// source other than its default value
+func requireOptions(options []*HandlerConfigOption) error {
+	for _, opt := range options {
+		if opt.Required && opt.source == sourceDefault {
+			return fmt.Errorf("required option %s was not set", opt.Argument)
+		}
+	}
+	return nil
+}
+
+// optionValue returns the value pointed to by the option's Value, or the raw
+// string value for options without a Value
+func optionValue(option *HandlerConfigOption) interface{} {
+	if option.Value == nil {
+		return option.rawValue
+	}
+	return reflect.Indirect(reflect.ValueOf(option.Value)).Interface()
+}
+
+func setOptionValue(option *HandlerConfigOption, valueStr string) error {
+	if option.Parse != nil {
+		if err := option.Parse(valueStr); err != nil {
+			return fmt.Errorf("Error parsing %s for option %s: %s", displayValue(option, valueStr), option.Argument, err)
+		}
+		return nil
+	}
+
+	switch option.Value.(type) {
+	case *string:
+		strOptionValue, ok := option.Value.(*string)
+		if ok {
+			*strOptionValue = valueStr
+		}
+	case *uint64:
+		uint64OptionPtrValue, ok := option.Value.(*uint64)
+		if ok {
+			parsedValue, err := strconv.ParseUint(valueStr, 10, 64)
+			if err != nil {
+				return fmt.Errorf("Error parsing %s into a uint64 for option %s", displayValue(option, valueStr), option.Argument)
+			}
+			*uint64OptionPtrValue = parsedValue
+		}
+	case *uint32:
+		uint32OptionPtrValue, ok := option.Value.(*uint32)
+		if ok {
+			parsedValue, err := strconv.ParseUint(valueStr, 10, 32)
+			if err != nil {
+				return parseIntError(option, valueStr, "uint32", err)
+			}
+			*uint32OptionPtrValue = uint32(parsedValue)
+		}
+	case *int:
+		intOptionPtrValue, ok := option.Value.(*int)
+		if ok {
+			parsedValue, err := strconv.ParseInt(valueStr, 10, 0)
+			if err != nil {
+				return fmt.Errorf("Error parsing %s into an int for option %s", displayValue(option, valueStr), option.Argument)
+			}
+			*intOptionPtrValue = int(parsedValue)
+		}
+	case *int32:
+		int32OptionPtrValue, ok := option.Value.(*int32)
+		if ok {
+			parsedValue, err := strconv.ParseInt(valueStr, 10, 32)
+			if err != nil {
+				return parseIntError(option, valueStr, "int32", err)
+			}
+			*int32OptionPtrValue = int32(parsedValue)
+		}
+	case *int64:
+		int64OptionPtrValue, ok := option.Value.(*int64)
+		if ok {
+			parsedValue, err := strconv.ParseInt(valueStr, 10, 64)
+			if err != nil {
+				return fmt.Errorf("Error parsing %s into an int64 for option %s", displayValue(option, valueStr), option.Argument)
+			}
+			*int64OptionPtrValue = parsedValue
+		}
+	case *float64:
+		float64OptionPtrValue, ok := option.Value.(*float64)
+		if ok {
+			parsedValue, err := strconv.ParseFloat(valueStr, 64)
+			if err != nil {
+				return fmt.Errorf("Error parsing %s into a float64 for option %s", displayValue(option, valueStr), option.Argument)
+			}
+			*float64OptionPtrValue = parsedValue
+		}
+	case *float32:
+		float32OptionPtrValue, ok := option.Value.(*float32)
+		if ok {
+			parsedValue, err := strconv.ParseFloat(valueStr, 32)
+			if err != nil {
+				return fmt.Errorf("Error parsing %s into a float32 for option %s", displayValue(option, valueStr), option.Argument)
+			}
+			*float32OptionPtrValue = float32(parsedValue)
+		}
+	case *time.Duration:
+		durationOptionPtrValue, ok := option.Value.(*time.Duration)
+		if ok {
+			parsedValue, err := time.ParseDuration(valueStr)
+			if err != nil {
+				return fmt.Errorf("Error parsing %s into a duration for option %s", displayValue(option, valueStr), option.Argument)
+			}
+			*durationOptionPtrValue = parsedValue
+		}
+	case *[]string:
+		stringSliceOptionPtrValue, ok := option.Value.(*[]string)
+		if ok {
+			parsedValue, err := parseStringSlice(valueStr)
+			if err != nil {
+				return fmt.Errorf("Error parsing %s into a string slice for option %s", displayValue(option, valueStr), option.Argument)
+			}
+			*stringSliceOptionPtrValue = parsedValue
+		}
+	case *bool:
+		boolOptionPtrValue, ok := option.Value.(*bool)
+		if ok {
+			parsedValue, err := strconv.ParseBool(valueStr)
+			if err != nil {
+				return fmt.Errorf("Error parsing %s into a bool for option %s", displayValue(option, valueStr), option.Argument)
+			}
+			*boolOptionPtrValue = parsedValue
+		}
+	case *net.IP:
+		ipOptionPtrValue, ok := option.Value.(*net.IP)
+		if ok {
+			parsedValue := net.ParseIP(valueStr)
+			if parsedValue == nil {
+				return fmt.Errorf("Error parsing %s into an IP address for option %s", displayValue(option, valueStr), option.Argument)
+			}
+			*ipOptionPtrValue = parsedValue
+		}
+	case *net.IPNet:
+		ipNetOptionPtrValue, ok := option.Value.(*net.IPNet)
+		if ok {
+			_, parsedValue, err := net.ParseCIDR(valueStr)
+			if err != nil {
+				return fmt.Errorf("Error parsing %s into a CIDR network for option %s", displayValue(option, valueStr), option.Argument)
+			}
+			*ipNetOptionPtrValue = *parsedValue
+		}
+	case *url.URL:
+		urlOptionPtrValue, ok := option.Value.(*url.URL)
+		if ok {
+			parsedValue, err := url.Parse(valueStr)
+			if err != nil || !parsedValue.IsAbs() || len(parsedValue.Host) == 0 {
+				return fmt.Errorf("Error parsing %s into an absolute URL for option %s", displayValue(option, valueStr), option.Argument)
+			}
+			if len(option.RequireScheme) > 0 && !containsFold(option.RequireScheme, parsedValue.Scheme) {
+				return fmt.Errorf("invalid scheme %q for option %s, must be one of %v", parsedValue.Scheme, option.Argument, option.RequireScheme)
+			}
+			*urlOptionPtrValue = *parsedValue
+		}
+	case *[]byte:
+		bytesOptionPtrValue, ok := option.Value.(*[]byte)
+		if ok {
+			parsedValue, err := decodeBytes(option.Encoding, valueStr)
+			if err != nil {
+				return fmt.Errorf("Error parsing %s into bytes for option %s: %s", displayValue(option, valueStr), option.Argument, err)
+			}
+			*bytesOptionPtrValue = parsedValue
+		}
+	case *map[string]string:
+		mapOptionPtrValue, ok := option.Value.(*map[string]string)
+		if ok {
+			parsedValue, err := parseStringMap(valueStr)
+			if err != nil {
+				return fmt.Errorf("Error parsing %s into a string map for option %s: %s", displayValue(option, valueStr), option.Argument, err)
+			}
+			*mapOptionPtrValue = parsedValue
+		}
+	default:
+		if isJSONOption(option) {
+			value := reflect.New(reflect.TypeOf(option.Value).Elem())
+			if err := json.Unmarshal([]byte(valueStr), value.Interface()); err != nil {
+				return fmt.Errorf("Error parsing %s into JSON for option %s: %s", displayValue(option, valueStr), option.Argument, err)
+			}
+			reflect.ValueOf(option.Value).Elem().Set(value.Elem())
+		}
+	}
+	return nil
+}
+
+// maskedValue is displayed instead of the value of Secret options
+const maskedValue = "****"
+
+// displayValue returns the value to show in logs and error messages for the
+// option, masking it if the option is a Secret
+func displayValue(option *HandlerConfigOption, value interface{}) interface{} {
+	if option.Secret {
+		return maskedValue
+	}
+	return value
+}
+
+// parseIntError returns the error of a value that could not be parsed into a
+// 32-bit integer type, telling overflows apart from invalid numbers
+func parseIntError(option *HandlerConfigOption, valueStr string, typeName string, err error) error {
+	article := "a"
+	if strings.HasPrefix(typeName, "int") {
+		article = "an"
+	}
+	if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+		return fmt.Errorf("Error parsing %s into %s %s for option %s: value out of range for %s",
+			displayValue(option, valueStr), article, typeName, option.Argument, typeName)
+	}
+	return fmt.Errorf("Error parsing %s into %s %s for option %s", displayValue(option, valueStr), article, typeName,
+		option.Argument)
+}
+
+// isJSONOption returns true if the option's Value points to a struct. Its
+// value, and Default, are JSON strings unmarshalled into the struct.
+func isJSONOption(option *HandlerConfigOption) bool {
+	if option.Parse != nil || option.Value == nil {
+		return false
+	}
+	switch option.Value.(type) {
+	case *net.IPNet, *url.URL:
+		return false
+	}
+	valueType := reflect.TypeOf(option.Value)
+	return valueType.Kind() == reflect.Ptr && valueType.Elem().Kind() == reflect.Struct
+}
+
+// isRawOption returns true if the option is registered as a string argument,
+// its value being parsed once resolved: options using Parse, JSON options and
+// byte slice, IP address, CIDR network, URL and string map options
+func isRawOption(option *HandlerConfigOption) bool {
+	if option.Parse != nil || isJSONOption(option) {
+		return true
+	}
+	switch option.Value.(type) {
+	case *[]byte, *net.IP, *net.IPNet, *url.URL, *map[string]string:
+		return true
+	}
+	return false
+}
+
+// rawDefault formats the Default of a raw option the way setOptionValue parses
+// it, the Default of a JSON option being marshalled unless it is a string
+func rawDefault(option *HandlerConfigOption) string {
+	switch defaultValue := option.Default.(type) {
+	case nil:
+		return ""
+	case string:
+		return defaultValue
+	case []byte:
+		if isBytesOption(option) {
+			return encodeBytes(option.Encoding, defaultValue)
+		}
+	case net.IPNet:
+		return defaultValue.String()
+	case url.URL:
+		return defaultValue.String()
+	case map[string]string:
+		return formatStringMap(defaultValue)
+	}
+
+	if isJSONOption(option) {
+		if jsonDefault, err := json.Marshal(option.Default); err == nil {
+			return string(jsonDefault)
+		}
+	}
+	return fmt.Sprint(option.Default)
+}
+
+// containsFold returns true if values contains value, ignoring the case
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Encodings of the values of []byte options
+const (
+	EncodingHex    = "hex"
+	EncodingBase64 = "base64"
+)
+
+// isBytesOption returns true if the option's Value points to a byte slice
+func isBytesOption(option *HandlerConfigOption) bool {
+	_, ok := option.Value.(*[]byte)
+	return ok && option.Parse == nil
+}
+
+// decodeBytes decodes the value of a []byte option
+func decodeBytes(encoding string, value string) ([]byte, error) {
+	switch encoding {
+	case "":
+		return []byte(value), nil
+	case EncodingHex:
+		return hex.DecodeString(value)
+	case EncodingBase64:
+		return base64.StdEncoding.DecodeString(value)
+	default:
+		return nil, fmt.Errorf("unknown encoding %q, must be one of [%s %s]", encoding, EncodingHex, EncodingBase64)
+	}
+}
+
+// encodeBytes encodes the value of a []byte option, the reverse of decodeBytes
+func encodeBytes(encoding string, value []byte) string {
+	switch encoding {
+	case EncodingHex:
+		return hex.EncodeToString(value)
+	case EncodingBase64:
+		return base64.StdEncoding.EncodeToString(value)
+	default:
+		return string(value)
+	}
+}
+
+// parseStringSlice splits a comma separated list into its trimmed, non-empty
+// elements. Elements containing a comma can be enclosed in double quotes.
+func parseStringSlice(valueStr string) ([]string, error) {
+	values := []string{}
+	valueStr = strings.TrimSpace(valueStr)
+	if valueStr == "" {
+		return values, nil
+	}
+
+	reader := csv.NewReader(strings.NewReader(valueStr))
+	reader.TrimLeadingSpace = true
+	record, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range record {
+		if value = strings.TrimSpace(value); len(value) > 0 {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+// parseStringMap parses a comma separated list of key=value pairs, trimming
+// the keys and values and skipping the empty entries. The last value of a
+// duplicate key wins.
+func parseStringMap(valueStr string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, entry := range strings.Split(valueStr, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) == 0 {
+			continue
+		}
+		pair := strings.SplitN(entry, "=", 2)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("entry %q is missing '='", entry)
+		}
+		key := strings.TrimSpace(pair[0])
+		if len(key) == 0 {
+			return nil, fmt.Errorf("entry %q has an empty key", entry)
+		}
+		values[key] = strings.TrimSpace(pair[1])
+	}
+	return values, nil
+}
+
+// formatStringMap formats a map the way parseStringMap parses it, sorted by key
+func formatStringMap(values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + values[key]
+	}
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,1579 @@
+package sensu
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetOptionValue_String(t *testing.T) {
+	finalValue := ""
+	option := defaultOption1
+	option.Value = &finalValue
+	err := setOptionValue(&option, "abc")
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", finalValue)
+}
+
+func TestSetOptionValue_EmptyString(t *testing.T) {
+	finalValue := ""
+	option := defaultOption1
+	option.Value = &finalValue
+	err := setOptionValue(&option, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "", finalValue)
+}
+
+func TestSetOptionValue_ValidUint64(t *testing.T) {
+	var finalValue uint64
+	option := defaultOption1
+	option.Value = &finalValue
+	err := setOptionValue(&option, "123")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(123), finalValue)
+}
+
+func TestSetOptionValue_InvalidUint64(t *testing.T) {
+	var finalValue uint64
+	option := defaultOption1
+	option.Value = &finalValue
+	err := setOptionValue(&option, "abc")
+	assert.NotNil(t, err)
+	assert.Equal(t, uint64(0), finalValue)
+}
+
+func TestSetOptionValue_ValidUint32(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected uint32
+	}{
+		{"123", 123},
+		{"0", 0},
+		{"4294967295", 4294967295},
+	}
+
+	for _, test := range tests {
+		var finalValue uint32
+		option := defaultOption1
+		option.Value = &finalValue
+		err := setOptionValue(&option, test.value)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, finalValue)
+	}
+}
+
+func TestSetOptionValue_InvalidUint32(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"abc", "Error parsing abc into a uint32 for option arg1"},
+		{"-1", "Error parsing -1 into a uint32 for option arg1"},
+		{"4294967296", "Error parsing 4294967296 into a uint32 for option arg1: value out of range for uint32"},
+	}
+
+	for _, test := range tests {
+		var finalValue uint32
+		option := defaultOption1
+		option.Value = &finalValue
+		err := setOptionValue(&option, test.value)
+		assert.EqualError(t, err, test.expected)
+		assert.Equal(t, uint32(0), finalValue)
+	}
+}
+
+func TestSetOptionValue_ValidInt32(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int32
+	}{
+		{"123", 123},
+		{"-5", -5},
+		{"2147483647", 2147483647},
+		{"-2147483648", -2147483648},
+	}
+
+	for _, test := range tests {
+		var finalValue int32
+		option := defaultOption1
+		option.Value = &finalValue
+		err := setOptionValue(&option, test.value)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, finalValue)
+	}
+}
+
+func TestSetOptionValue_InvalidInt32(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"1.5", "Error parsing 1.5 into an int32 for option arg1"},
+		{"2147483648", "Error parsing 2147483648 into an int32 for option arg1: value out of range for int32"},
+		{"-2147483649", "Error parsing -2147483649 into an int32 for option arg1: value out of range for int32"},
+	}
+
+	for _, test := range tests {
+		var finalValue int32
+		option := defaultOption1
+		option.Value = &finalValue
+		err := setOptionValue(&option, test.value)
+		assert.EqualError(t, err, test.expected)
+		assert.Equal(t, int32(0), finalValue)
+	}
+}
+
+func TestGoHandler_Execute_32BitOptions(t *testing.T) {
+	var port uint32
+	var offset int32
+	clearEnvironment()
+	options := []*HandlerConfigOption{
+		{Argument: "port", Path: "path1", Value: &port, Default: uint32(8080)},
+		{Argument: "offset", Path: "path2", Value: &offset, Default: int32(-1)},
+	}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{"--offset", "-20"})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(8080), port)
+	assert.Equal(t, int32(-20), offset)
+
+	goHandler = NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{"--port", "4294967296"})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err = goHandler.Execute()
+
+	assert.NotNil(t, err)
+}
+
+func TestSetOptionValue_ValidInt(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int
+	}{
+		{"123", 123},
+		{"0", 0},
+		{"-5", -5},
+	}
+
+	for _, test := range tests {
+		var finalValue int
+		option := defaultOption1
+		option.Value = &finalValue
+		err := setOptionValue(&option, test.value)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, finalValue)
+	}
+}
+
+func TestSetOptionValue_InvalidInt(t *testing.T) {
+	tests := []string{"abc", "", "1.5", "99999999999999999999"}
+
+	for _, value := range tests {
+		var finalValue int
+		option := defaultOption1
+		option.Value = &finalValue
+		err := setOptionValue(&option, value)
+		assert.NotNil(t, err)
+		assert.Equal(t, 0, finalValue)
+	}
+}
+
+func TestSetOptionValue_ValidInt64(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int64
+	}{
+		{"123", 123},
+		{"0", 0},
+		{"-5", -5},
+		{"-9223372036854775808", -9223372036854775808},
+	}
+
+	for _, test := range tests {
+		var finalValue int64
+		option := defaultOption1
+		option.Value = &finalValue
+		err := setOptionValue(&option, test.value)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, finalValue)
+	}
+}
+
+func TestSetOptionValue_InvalidInt64(t *testing.T) {
+	tests := []string{"abc", "", "1.5", "9223372036854775808"}
+
+	for _, value := range tests {
+		var finalValue int64
+		option := defaultOption1
+		option.Value = &finalValue
+		err := setOptionValue(&option, value)
+		assert.NotNil(t, err)
+		assert.Equal(t, int64(0), finalValue)
+	}
+}
+
+func TestSetOptionValue_ValidFloat64(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected float64
+	}{
+		{"3.14", 3.14},
+		{"0", 0},
+		{"1e3", 1000},
+		{"-3.14", -3.14},
+	}
+
+	for _, test := range tests {
+		var finalValue float64
+		option := defaultOption1
+		option.Value = &finalValue
+		err := setOptionValue(&option, test.value)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, finalValue)
+	}
+}
+
+func TestSetOptionValue_InvalidFloat64(t *testing.T) {
+	tests := []string{"", "1.2.3", "abc"}
+
+	for _, value := range tests {
+		var finalValue float64
+		option := defaultOption1
+		option.Value = &finalValue
+		err := setOptionValue(&option, value)
+		assert.NotNil(t, err)
+		assert.Equal(t, float64(0), finalValue)
+	}
+}
+
+func TestSetOptionValue_ValidFloat32(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected float32
+	}{
+		{"3.14", 3.14},
+		{"0", 0},
+	}
+
+	for _, test := range tests {
+		var finalValue float32
+		option := defaultOption1
+		option.Value = &finalValue
+		err := setOptionValue(&option, test.value)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, finalValue)
+	}
+}
+
+func TestSetOptionValue_InvalidFloat32(t *testing.T) {
+	tests := []string{"", "1.2.3", "abc", "1e39"}
+
+	for _, value := range tests {
+		var finalValue float32
+		option := defaultOption1
+		option.Value = &finalValue
+		err := setOptionValue(&option, value)
+		assert.NotNil(t, err)
+		assert.Equal(t, float32(0), finalValue)
+	}
+}
+
+func TestSetOptionValue_ValidDuration(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"30s", 30 * time.Second},
+		{"5m", 5 * time.Minute},
+		{"1h500ms", time.Hour + 500*time.Millisecond},
+		{"1.5s", 1500 * time.Millisecond},
+		{"250us", 250 * time.Microsecond},
+		{"0", 0},
+		{"0s", 0},
+		{"-5m", -5 * time.Minute},
+		{"-1h30m", -(time.Hour + 30*time.Minute)},
+	}
+
+	for _, test := range tests {
+		var finalValue time.Duration
+		option := defaultOption1
+		option.Value = &finalValue
+		err := setOptionValue(&option, test.value)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, finalValue)
+	}
+}
+
+func TestSetOptionValue_InvalidDuration(t *testing.T) {
+	tests := []string{"", "30", "5 minutes", "1x"}
+
+	for _, value := range tests {
+		var finalValue time.Duration
+		option := defaultOption1
+		option.Value = &finalValue
+		err := setOptionValue(&option, value)
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "for option arg1")
+		assert.Equal(t, time.Duration(0), finalValue)
+	}
+}
+
+func TestSetOptionValue_StringSlice(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []string
+	}{
+		{"", []string{}},
+		{"abc", []string{"abc"}},
+		{"a,b,c", []string{"a", "b", "c"}},
+		{" a , b ,c ", []string{"a", "b", "c"}},
+		{`a,"b,c",d`, []string{"a", "b,c", "d"}},
+		{`a, "b,c"`, []string{"a", "b,c"}},
+		{`"say ""hi""",b`, []string{`say "hi"`, "b"}},
+	}
+
+	for _, test := range tests {
+		var finalValue []string
+		option := defaultOption1
+		option.Value = &finalValue
+		err := setOptionValue(&option, test.value)
+		assert.Nil(t, err)
+		assert.NotNil(t, finalValue)
+		assert.Equal(t, test.expected, finalValue)
+	}
+}
+
+func TestSetOptionValue_InvalidStringSlice(t *testing.T) {
+	finalValue := []string{"unchanged"}
+	option := defaultOption1
+	option.Value = &finalValue
+	err := setOptionValue(&option, `a,"b`)
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"unchanged"}, finalValue)
+}
+
+func TestSetOptionValue_Bytes(t *testing.T) {
+	tests := []struct {
+		encoding    string
+		value       string
+		expected    []byte
+		expectedErr string
+	}{
+		{EncodingHex, "deadBEEF", []byte{0xde, 0xad, 0xbe, 0xef}, ""},
+		{EncodingHex, "", []byte{}, ""},
+		{EncodingHex, "abc", nil, "Error parsing abc into bytes for option arg1: encoding/hex: odd length hex string"},
+		{EncodingHex, "zz", nil, "Error parsing zz into bytes for option arg1: encoding/hex: invalid byte: U+007A 'z'"},
+		{EncodingBase64, "3q2+7w==", []byte{0xde, 0xad, 0xbe, 0xef}, ""},
+		{EncodingBase64, "3q2+7w", nil, "Error parsing 3q2+7w into bytes for option arg1: illegal base64 data at input byte 4"},
+		{EncodingBase64, "3q2*7w==", nil, "Error parsing 3q2*7w== into bytes for option arg1: illegal base64 data at input byte 3"},
+		{"", "key", []byte("key"), ""},
+		{"base32", "key", nil, `Error parsing key into bytes for option arg1: unknown encoding "base32", must be one of [hex base64]`},
+	}
+
+	for _, test := range tests {
+		var finalValue []byte
+		option := defaultOption1
+		option.Value = &finalValue
+		option.Encoding = test.encoding
+		err := setOptionValue(&option, test.value)
+		if len(test.expectedErr) > 0 {
+			assert.EqualError(t, err, test.expectedErr)
+			assert.Nil(t, finalValue)
+		} else {
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, finalValue)
+		}
+	}
+}
+
+func TestGoHandler_Execute_BytesOption(t *testing.T) {
+	var signingKey, defaultKey []byte
+	clearEnvironment()
+	options := []*HandlerConfigOption{
+		{Argument: "signing-key", Value: &signingKey, Encoding: EncodingBase64, Secret: true},
+		{Argument: "default-key", Value: &defaultKey, Encoding: EncodingHex, Default: []byte{0xca, 0xfe}},
+	}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) error {
+		return nil
+	})
+	goHandler.cmdArgs.SetArgs([]string{"--signing-key", "3q2+7w=="})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0xde, 0xad, 0xbe, 0xef}, signingKey)
+	assert.Equal(t, []byte{0xca, 0xfe}, defaultKey)
+	assert.Contains(t, goHandler.OptionsAsEnv(), "DEFAULT_KEY=cafe")
+
+	goHandler = NewGoHandler(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) error {
+		return nil
+	})
+	goHandler.cmdArgs.SetArgs([]string{"--signing-key", "not base64"})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err = goHandler.Execute()
+
+	assert.EqualError(t, err, "Error parsing **** into bytes for option signing-key: illegal base64 data at input byte 3")
+}
+
+func TestSetOptionValue_IP(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    net.IP
+		expectedErr string
+	}{
+		{"192.168.1.10", net.ParseIP("192.168.1.10"), ""},
+		{"2001:db8::68", net.ParseIP("2001:db8::68"), ""},
+		{"192.168.1.256", nil, "Error parsing 192.168.1.256 into an IP address for option arg1"},
+		{"192.168.1.0/24", nil, "Error parsing 192.168.1.0/24 into an IP address for option arg1"},
+		{"example.com", nil, "Error parsing example.com into an IP address for option arg1"},
+	}
+
+	for _, test := range tests {
+		var finalValue net.IP
+		option := defaultOption1
+		option.Value = &finalValue
+		err := setOptionValue(&option, test.value)
+		if len(test.expectedErr) > 0 {
+			assert.EqualError(t, err, test.expectedErr)
+		} else {
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, test.expected, finalValue)
+	}
+}
+
+func TestSetOptionValue_IPNet(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    string
+		expectedErr string
+	}{
+		{"10.0.0.0/8", "10.0.0.0/8", ""},
+		{"192.168.1.10/24", "192.168.1.0/24", ""},
+		{"2001:db8::/32", "2001:db8::/32", ""},
+		{"10.0.0.1", "<nil>", "Error parsing 10.0.0.1 into a CIDR network for option arg1"},
+		{"10.0.0.0/33", "<nil>", "Error parsing 10.0.0.0/33 into a CIDR network for option arg1"},
+		{"2001:db8::/129", "<nil>", "Error parsing 2001:db8::/129 into a CIDR network for option arg1"},
+	}
+
+	for _, test := range tests {
+		var finalValue net.IPNet
+		option := defaultOption1
+		option.Value = &finalValue
+		err := setOptionValue(&option, test.value)
+		if len(test.expectedErr) > 0 {
+			assert.EqualError(t, err, test.expectedErr)
+		} else {
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, test.expected, finalValue.String())
+	}
+}
+
+func TestGoHandler_Execute_IPOptions(t *testing.T) {
+	var target net.IP
+	var allowlist net.IPNet
+	clearEnvironment()
+	options := []*HandlerConfigOption{
+		{Argument: "target", Value: &target, Default: net.ParseIP("127.0.0.1")},
+		{Argument: "allowlist", Value: &allowlist, Default: net.IPNet{IP: net.IPv4(10, 0, 0, 0), Mask: net.CIDRMask(8, 32)}},
+	}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) error {
+		return nil
+	})
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1", target.String())
+	assert.Equal(t, "10.0.0.0/8", allowlist.String())
+	assert.Equal(t, []string{"TARGET=127.0.0.1", "ALLOWLIST=10.0.0.0/8"}, goHandler.OptionsAsEnv())
+
+	goHandler = NewGoHandler(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) error {
+		return nil
+	})
+	goHandler.cmdArgs.SetArgs([]string{"--target", "fe80::1", "--allowlist", "fe80::/10"})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err = goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "fe80::1", target.String())
+	assert.Equal(t, "fe80::/10", allowlist.String())
+}
+
+func TestSetOptionValue_URL(t *testing.T) {
+	tests := []struct {
+		value         string
+		requireScheme []string
+		expected      string
+		expectedErr   string
+	}{
+		{"https://hooks.example.com/services/T000?token=abc", nil, "https://hooks.example.com/services/T000?token=abc", ""},
+		{"https://hooks.example.com/services", []string{"http", "https"}, "https://hooks.example.com/services", ""},
+		{"HTTPS://hooks.example.com", []string{"https"}, "https://hooks.example.com", ""},
+		{"ftp://files.example.com/upload", []string{"http", "https"}, "",
+			`invalid scheme "ftp" for option arg1, must be one of [http https]`},
+		{"not a url", nil, "", "Error parsing not a url into an absolute URL for option arg1"},
+		{"/services/T000", nil, "", "Error parsing /services/T000 into an absolute URL for option arg1"},
+		{"https://", nil, "", "Error parsing https:// into an absolute URL for option arg1"},
+		{"https://hooks.example.com/%zz", nil, "", "Error parsing https://hooks.example.com/%zz into an absolute URL for option arg1"},
+	}
+
+	for _, test := range tests {
+		var finalValue url.URL
+		option := defaultOption1
+		option.Value = &finalValue
+		option.RequireScheme = test.requireScheme
+		err := setOptionValue(&option, test.value)
+		if len(test.expectedErr) > 0 {
+			assert.EqualError(t, err, test.expectedErr)
+		} else {
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, test.expected, finalValue.String())
+	}
+}
+
+func TestGoHandler_Execute_URLOption(t *testing.T) {
+	var webhook url.URL
+	clearEnvironment()
+	defaultWebhook, err := url.Parse("https://hooks.example.com/default")
+	assert.Nil(t, err)
+	options := []*HandlerConfigOption{
+		{Argument: "webhook", Path: "webhook", Value: &webhook, Default: *defaultWebhook, RequireScheme: []string{"https"}},
+	}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) error {
+		return nil
+	})
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err = goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "https://hooks.example.com/default", webhook.String())
+	assert.Equal(t, []string{"WEBHOOK=https://hooks.example.com/default"}, goHandler.OptionsAsEnv())
+	assert.Equal(t, "url.URL", goHandler.Metadata().Options[0].Type)
+
+	goHandler = NewGoHandler(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) error {
+		return nil
+	})
+	goHandler.cmdArgs.SetArgs([]string{"--webhook", "http://hooks.example.com/insecure"})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err = goHandler.Execute()
+
+	assert.EqualError(t, err, `invalid scheme "http" for option webhook, must be one of [https]`)
+}
+
+func TestApplyDefault(t *testing.T) {
+	var stringValue string
+	var uint64Value uint64
+	var intValue int
+	var int64Value int64
+	var float64Value float64
+	var float32Value float32
+	var durationValue time.Duration
+	var sliceValue []string
+	var boolValue bool
+	options := []*HandlerConfigOption{
+		{Argument: "string", Value: &stringValue, Default: "abc"},
+		{Argument: "uint64", Value: &uint64Value, Default: uint64(18446744073709551615)},
+		{Argument: "int", Value: &intValue, Default: -42},
+		{Argument: "int64", Value: &int64Value, Default: int64(-9223372036854775808)},
+		{Argument: "float64", Value: &float64Value, Default: 0.1},
+		{Argument: "float32", Value: &float32Value, Default: float32(0.1)},
+		{Argument: "duration", Value: &durationValue, Default: 90 * time.Second},
+		{Argument: "slice", Value: &sliceValue, Default: []string{"a", "b,c", `say "hi"`}},
+		{Argument: "bool", Value: &boolValue, Default: true},
+	}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) error {
+		return nil
+	})
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	for _, option := range options {
+		assert.Equal(t, option.Default, reflect.Indirect(reflect.ValueOf(option.Value)).Interface(), option.Argument)
+		assert.Equal(t, sourceDefault, option.source, option.Argument)
+	}
+}
+
+func ipParser(ip *net.IP) func(string) error {
+	return func(value string) error {
+		parsedIP := net.ParseIP(value)
+		if parsedIP == nil {
+			return fmt.Errorf("invalid IP address")
+		}
+		*ip = parsedIP
+		return nil
+	}
+}
+
+func TestSetOptionValue_CustomParser(t *testing.T) {
+	var finalValue net.IP
+	option := defaultOption1
+	option.Parse = ipParser(&finalValue)
+	err := setOptionValue(&option, "10.0.0.1")
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.1", finalValue.String())
+}
+
+func TestSetOptionValue_CustomParserError(t *testing.T) {
+	var finalValue net.IP
+	option := defaultOption1
+	option.Parse = ipParser(&finalValue)
+	err := setOptionValue(&option, "not-an-ip")
+	assert.EqualError(t, err, "Error parsing not-an-ip for option arg1: invalid IP address")
+	assert.Nil(t, finalValue)
+}
+
+type routingTarget struct {
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
+}
+
+type routingRules struct {
+	Default string `json:"default"`
+	Routes  []struct {
+		Match  string        `json:"match"`
+		Target routingTarget `json:"target"`
+	} `json:"routes"`
+}
+
+func TestSetOptionValue_JSON(t *testing.T) {
+	var rules routingRules
+	option := defaultOption1
+	option.Value = &rules
+	err := setOptionValue(&option, `{"default": "email", "routes": [{"match": "disk", "target": {"name": "ops", "priority": 2}}]}`)
+	assert.Nil(t, err)
+	assert.Equal(t, "email", rules.Default)
+	assert.Equal(t, 1, len(rules.Routes))
+	assert.Equal(t, "disk", rules.Routes[0].Match)
+	assert.Equal(t, routingTarget{Name: "ops", Priority: 2}, rules.Routes[0].Target)
+
+	// A new value replaces the previous one
+	err = setOptionValue(&option, `{"default": "slack"}`)
+	assert.Nil(t, err)
+	assert.Equal(t, "slack", rules.Default)
+	assert.Empty(t, rules.Routes)
+}
+
+func TestSetOptionValue_InvalidJSON(t *testing.T) {
+	rules := routingRules{Default: "email"}
+	option := defaultOption1
+	option.Value = &rules
+	err := setOptionValue(&option, `{"default": `)
+	assert.EqualError(t, err, `Error parsing {"default":  into JSON for option arg1: unexpected end of JSON input`)
+	assert.Equal(t, "email", rules.Default)
+}
+
+func TestGoHandler_Execute_JSONOption(t *testing.T) {
+	tests := []struct {
+		eventFile       string
+		args            []string
+		expectedDefault string
+		expectedRoutes  int
+	}{
+		{"test/event-no-override.json", []string{}, "email", 0},
+		{"test/event-no-override.json", []string{"--rules", `{"default": "opsgenie"}`}, "opsgenie", 0},
+		{"test/event-json-override.json", []string{"--rules", `{"default": "opsgenie"}`}, "slack", 1},
+	}
+
+	for _, test := range tests {
+		var rules routingRules
+		clearEnvironment()
+		options := []*HandlerConfigOption{{
+			Path:     "rules",
+			Argument: "rules",
+			Default:  `{"default": "email"}`,
+			Usage:    "Routing rules",
+			Value:    &rules,
+		}}
+
+		goHandler := NewGoHandler(&defaultHandlerConfig, options,
+			func(event *types.Event) error {
+				return nil
+			}, func(event *types.Event) error {
+				return nil
+			})
+		goHandler.cmdArgs.SetArgs(test.args)
+		goHandler.eventReader = getFileReader(test.eventFile)
+		err := goHandler.Execute()
+
+		assert.Nil(t, err)
+		assert.Equal(t, test.expectedDefault, rules.Default)
+		assert.Equal(t, test.expectedRoutes, len(rules.Routes))
+	}
+}
+
+// Test a struct Default of a JSON option is used when no other source sets it
+func TestGoHandler_Execute_JSONOptionStructDefault(t *testing.T) {
+	var rules routingRules
+	clearEnvironment()
+	defaultRules := routingRules{Default: "email"}
+	defaultRules.Routes = append(defaultRules.Routes, struct {
+		Match  string        `json:"match"`
+		Target routingTarget `json:"target"`
+	}{Match: "disk", Target: routingTarget{Name: "ops", Priority: 2}})
+	options := []*HandlerConfigOption{{
+		Path:     "rules",
+		Argument: "rules",
+		Default:  defaultRules,
+		Usage:    "Routing rules",
+		Value:    &rules,
+	}}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, defaultRules, rules)
+}
+
+func TestGoHandler_OptionsAsEnv_JSON(t *testing.T) {
+	rules := routingRules{Default: "slack"}
+	options := []*HandlerConfigOption{{Argument: "rules", Env: "RULES", Value: &rules}}
+	goHandler := NewGoHandler(&defaultHandlerConfig, options, nil, nil)
+
+	assert.Equal(t, []string{`RULES={"default":"slack","routes":null}`}, goHandler.OptionsAsEnv())
+}
+
+// Test slice values with commas and quotes survive a round trip through the
+// environment
+func TestGoHandler_OptionsAsEnv_StringSlice(t *testing.T) {
+	tags := []string{"a", "b,c", `say "hi"`}
+	options := []*HandlerConfigOption{{Argument: "tags", Env: "TAGS", Value: &tags}}
+	goHandler := NewGoHandler(&defaultHandlerConfig, options, nil, nil)
+
+	env := goHandler.OptionsAsEnv()
+	assert.Equal(t, []string{`TAGS=a,"b,c","say ""hi"""`}, env)
+
+	values, err := parseStringSlice(strings.TrimPrefix(env[0], "TAGS="))
+	assert.Nil(t, err)
+	assert.Equal(t, tags, values)
+}
+
+func TestSetOptionValue_TrueBool(t *testing.T) {
+	var finalValue bool
+	option := defaultOption1
+	option.Value = &finalValue
+	err := setOptionValue(&option, "true")
+	assert.Nil(t, err)
+	assert.Equal(t, true, finalValue)
+}
+
+func TestSetOptionValue_FalseBool(t *testing.T) {
+	finalValue := true
+	option := defaultOption1
+	option.Value = &finalValue
+	err := setOptionValue(&option, "false")
+	assert.Nil(t, err)
+	assert.Equal(t, false, finalValue)
+}
+
+func TestSetOptionValue_InvalidBool(t *testing.T) {
+	var finalValue bool
+	option := defaultOption1
+	option.Value = &finalValue
+	err := setOptionValue(&option, "yes")
+	assert.NotNil(t, err)
+	assert.Equal(t, false, finalValue)
+}
+
+// Test required options satisfied by each source or missing
+// Test a required option without a default
+func TestGoHandler_Execute_RequiredOptionNilDefault(t *testing.T) {
+	tests := []struct {
+		cmdLineArgs []string
+		expectedErr string
+	}{
+		{[]string{"--arg1", "value-arg1"}, ""},
+		{[]string{}, "required option arg1 was not set"},
+	}
+
+	for _, test := range tests {
+		clearEnvironment()
+		options := getDefaultOptions()
+		values := handlerValues{}
+		options[0].Value = &values.arg1
+		options[0].Required = true
+		options[0].Default = nil
+		options[1].Value = &values.arg2
+		options[2].Value = &values.arg3
+
+		goHandler := NewGoHandler(&defaultHandlerConfig, options,
+			func(event *types.Event) error {
+				return nil
+			}, func(event *types.Event) error {
+				return nil
+			})
+		goHandler.cmdArgs.SetArgs(test.cmdLineArgs)
+		goHandler.eventReader = getFileReader("test/event-no-override.json")
+		err := goHandler.Execute()
+
+		if len(test.expectedErr) > 0 {
+			assert.EqualError(t, err, test.expectedErr)
+		} else {
+			assert.Nil(t, err)
+			assert.Equal(t, "value-arg1", values.arg1)
+		}
+	}
+}
+
+func TestGoHandler_Execute_RequiredOption(t *testing.T) {
+	tests := []struct {
+		eventFile   string
+		cmdLineArgs []string
+		env         string
+		expectedErr string
+	}{
+		{"test/event-check-override.json", []string{}, "", ""},
+		{"test/event-entity-override.json", []string{}, "", ""},
+		{"test/event-no-override.json", []string{"--arg1", "value-arg1"}, "", ""},
+		{"test/event-no-override.json", []string{}, "value-env1", ""},
+		{"test/event-no-override.json", []string{}, "", "required option arg1 was not set"},
+	}
+
+	for _, test := range tests {
+		var validateCalled bool
+		clearEnvironment()
+		if len(test.env) > 0 {
+			_ = os.Setenv("ENV_1", test.env)
+		}
+		options := getDefaultOptions()
+		values := handlerValues{}
+		options[0].Value = &values.arg1
+		options[0].Required = true
+		options[1].Value = &values.arg2
+		options[2].Value = &values.arg3
+
+		goHandler := NewGoHandler(&defaultHandlerConfig, options,
+			func(event *types.Event) error {
+				validateCalled = true
+				return nil
+			}, func(event *types.Event) error {
+				return nil
+			})
+		goHandler.cmdArgs.SetArgs(test.cmdLineArgs)
+		goHandler.eventReader = getFileReader(test.eventFile)
+		err := goHandler.Execute()
+		clearEnvironment()
+
+		if len(test.expectedErr) > 0 {
+			assert.EqualError(t, err, test.expectedErr)
+			assert.False(t, validateCalled)
+		} else {
+			assert.Nil(t, err)
+			assert.True(t, validateCalled)
+		}
+	}
+}
+
+// Test required options explicitly set to their zero value are satisfied
+func TestGoHandler_Execute_RequiredOptionZeroValue(t *testing.T) {
+	tests := []struct {
+		cmdLineArgs []string
+		env         string
+		expectedErr string
+	}{
+		{[]string{"--arg2", "0", "--arg3=false"}, "", ""},
+		{[]string{"--arg3=false"}, "0", ""},
+		{[]string{"--arg3=false"}, "", "required option arg2 was not set"},
+		{[]string{"--arg2", "0"}, "", "required option arg3 was not set"},
+	}
+
+	for _, test := range tests {
+		clearEnvironment()
+		if len(test.env) > 0 {
+			_ = os.Setenv("ENV_2", test.env)
+		}
+		options := getDefaultOptions()
+		values := handlerValues{}
+		options[0].Value = &values.arg1
+		options[1].Value = &values.arg2
+		options[1].Default = uint64(0)
+		options[1].Required = true
+		options[2].Value = &values.arg3
+		options[2].Required = true
+
+		goHandler := NewGoHandler(&defaultHandlerConfig, options,
+			func(event *types.Event) error {
+				return nil
+			}, func(event *types.Event) error {
+				return nil
+			})
+		goHandler.cmdArgs.SetArgs(test.cmdLineArgs)
+		goHandler.eventReader = getFileReader("test/event-no-override.json")
+		err := goHandler.Execute()
+		clearEnvironment()
+
+		if len(test.expectedErr) > 0 {
+			assert.EqualError(t, err, test.expectedErr, test.cmdLineArgs)
+		} else {
+			assert.Nil(t, err, test.cmdLineArgs)
+			assert.Equal(t, uint64(0), values.arg2)
+			assert.False(t, values.arg3)
+		}
+	}
+}
+
+func TestDisplayValue_AllowedValues(t *testing.T) {
+	value := "s3cr3t"
+	option := defaultOption1
+	option.Value = &value
+	option.Secret = true
+	option.AllowedValues = []string{"a", "b"}
+
+	err := checkAllowedValues(&option)
+	assert.EqualError(t, err, `invalid value "****" for arg1, must be one of [a b]`)
+
+	option.Secret = false
+	assert.Equal(t, "s3cr3t", displayValue(&option, value))
+}
+
+// Test environment variable alternatives
+func TestGoHandler_Execute_EnvAlternatives(t *testing.T) {
+	var logOutput bytes.Buffer
+	clearEnvironment()
+	_ = os.Setenv("ENV_ALT_2", "value-alt2")
+	_ = os.Setenv("ENV_ALT_3", "value-alt3")
+
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[0].EnvAlternatives = []string{"ENV_ALT_1", "ENV_ALT_2", "ENV_ALT_3"}
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		}, WithErrorWriter(&logOutput))
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+	_ = os.Unsetenv("ENV_ALT_2")
+	_ = os.Unsetenv("ENV_ALT_3")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "value-alt2", values.arg1)
+	assert.Equal(t, sourceEnv, options[0].source)
+	assert.Contains(t, logOutput.String(), "Reading option arg1 from environment variable ENV_ALT_2")
+}
+
+// Test the primary environment variable wins over its alternatives
+func TestGoHandler_Execute_CaseInsensitiveEnv(t *testing.T) {
+	tests := []struct {
+		name            string
+		env             string
+		caseInsensitive bool
+		expected        string
+		source          string
+	}{
+		{"exact match", "ENV_1", false, "value-env1", sourceEnv},
+		{"exact match case insensitive", "ENV_1", true, "value-env1", sourceEnv},
+		{"case folded match", "env_1", true, "value-env1", sourceEnv},
+		{"strict mode rejects mismatched case", "env_1", false, "Default1", sourceDefault},
+	}
+
+	for _, test := range tests {
+		clearEnvironment()
+		_ = os.Setenv(test.env, "value-env1")
+		handlerConfig := defaultHandlerConfig
+		handlerConfig.CaseInsensitiveEnv = test.caseInsensitive
+		options := getDefaultOptions()
+		values := handlerValues{}
+		options[0].Value = &values.arg1
+		options[1].Value = &values.arg2
+		options[2].Value = &values.arg3
+
+		goHandler := NewGoHandler(&handlerConfig, options,
+			func(event *types.Event) error {
+				return nil
+			}, func(event *types.Event) error {
+				return nil
+			})
+		goHandler.cmdArgs.SetArgs([]string{})
+		goHandler.eventReader = getFileReader("test/event-no-override.json")
+		err := goHandler.Execute()
+		_ = os.Unsetenv(test.env)
+
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.expected, values.arg1, test.name)
+		assert.Equal(t, test.source, options[0].source, test.name)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	clearEnvironment()
+	_ = os.Setenv("env_alt_1", "value-alt1")
+	defer os.Unsetenv("env_alt_1")
+
+	assert.Equal(t, "ENV_ALT_1", envName("ENV_ALT_1", false))
+	assert.Equal(t, "env_alt_1", envName("ENV_ALT_1", true))
+	assert.Equal(t, "ENV_1", envName("ENV_1", true))
+	assert.Equal(t, "", envName("", true))
+}
+
+func TestCheckDuplicateOptions(t *testing.T) {
+	options := getDefaultOptions()
+	assert.Nil(t, checkDuplicateOptions(options))
+
+	options[1].Shorthand = ""
+	options[2].Shorthand = ""
+	assert.Nil(t, checkDuplicateOptions(options))
+
+	options = getDefaultOptions()
+	options[2].Shorthand = "d"
+	assert.EqualError(t, checkDuplicateOptions(options), "duplicate shorthand 'd' for options arg1 and arg3")
+
+	options = getDefaultOptions()
+	options[1].Argument = "arg1"
+	assert.EqualError(t, checkDuplicateOptions(options), "duplicate argument 'arg1' for options 1 and 2")
+
+	for _, reserved := range []string{"batch", "config", "dry-run", "event-file", "help", "input-format",
+		"keyspace", "log-level", "output", "version"} {
+		options = getDefaultOptions()
+		options[1].Argument = reserved
+		assert.EqualError(t, checkDuplicateOptions(options),
+			fmt.Sprintf("argument '%s' of option 2 is reserved for the built-in --%s flag", reserved, reserved))
+	}
+}
+
+// Test an option named like a built-in flag is rejected instead of making the
+// flag registration panic or shadowing the flag
+func TestGoHandler_Execute_ReservedArgument(t *testing.T) {
+	for _, reserved := range []string{"output", "config"} {
+		clearEnvironment()
+		options := getDefaultOptions()
+		values := handlerValues{}
+		options[0].Value = &values.arg1
+		options[1].Value = &values.arg2
+		options[2].Value = &values.arg3
+		options[0].Argument = reserved
+		goHandler := NewGoHandler(&defaultHandlerConfig, options, func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+		goHandler.cmdArgs.SetArgs([]string{})
+		goHandler.eventReader = getFileReader("test/event-no-override.json")
+
+		assert.EqualError(t, goHandler.Execute(),
+			fmt.Sprintf("argument '%s' of option 1 is reserved for the built-in --%s flag", reserved, reserved))
+	}
+}
+
+func TestGoHandler_Execute_DuplicateShorthand(t *testing.T) {
+	var executeCalled bool
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+	options[2].Shorthand = "e"
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.EqualError(t, err, "duplicate shorthand 'e' for options arg2 and arg3")
+	assert.False(t, executeCalled)
+}
+
+func TestResolveEnv(t *testing.T) {
+	clearEnvironment()
+	option := defaultOption1
+	option.EnvAlternatives = []string{"ENV_ALT_1"}
+	_ = os.Setenv("ENV_ALT_1", "value-alt1")
+	assert.Equal(t, "ENV_ALT_1", resolveEnv(&option, false, log.New(ioutil.Discard, "", 0)))
+
+	_ = os.Setenv("ENV_1", "value-env1")
+	assert.Equal(t, "ENV_1", resolveEnv(&option, false, log.New(ioutil.Discard, "", 0)))
+
+	_ = os.Unsetenv("ENV_ALT_1")
+	clearEnvironment()
+	assert.Equal(t, "ENV_1", resolveEnv(&option, false, log.New(ioutil.Discard, "", 0)))
+}
+
+func TestGoHandler_Execute_NoOptionValue(t *testing.T) {
+	options := getDefaultOptions()
+	handlerConfig := defaultHandlerConfig
+
+	goHandler := NewGoHandler(&handlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+
+	goHandler.cmdArgs.SetArgs(defaultCmdLineArgs)
+
+	// Replace stdin reader with file reader
+	goHandler.eventReader = getFileReader("test/event-check-entity-override.json")
+	err := goHandler.Execute()
+
+	assert.NotNil(t, err)
+	assert.Errorf(t, err, "Option value must not be nil for option arg1")
+}
+
+func TestCheckDefaultType(t *testing.T) {
+	var stringValue string
+	option := defaultOption1
+	option.Value = &stringValue
+	assert.Nil(t, checkDefaultType(&option))
+
+	option.Argument = "arg2"
+	option.Default = uint64(33333)
+	assert.EqualError(t, checkDefaultType(&option), "option arg2: default type uint64 incompatible with value type *string")
+
+	option.Default = nil
+	assert.EqualError(t, checkDefaultType(&option), "option arg2: default type <nil> incompatible with value type *string")
+
+	option.Required = true
+	assert.Nil(t, checkDefaultType(&option))
+}
+
+func TestGoHandler_Execute_DefaultTypeMismatch(t *testing.T) {
+	var executeCalled bool
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	var stringValue string
+	options[0].Value = &values.arg1
+	options[1].Value = &stringValue
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.EqualError(t, err, "option arg2: default type uint64 incompatible with value type *string")
+	assert.False(t, executeCalled)
+}
+
+func TestGoHandler_Execute_SignedIntegerOptions(t *testing.T) {
+	var intValue int
+	var int64Value int64
+	clearEnvironment()
+	intOption := HandlerConfigOption{
+		Argument: "int-arg",
+		Default:  10,
+		Usage:    "Int argument",
+		Value:    &intValue,
+	}
+	int64Option := HandlerConfigOption{
+		Argument: "int64-arg",
+		Default:  int64(20),
+		Usage:    "Int64 argument",
+		Value:    &int64Value,
+	}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, []*HandlerConfigOption{&intOption, &int64Option},
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{"--int-arg=-5"})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, -5, intValue)
+	assert.Equal(t, int64(20), int64Value)
+}
+
+func TestGoHandler_Execute_FloatOptions(t *testing.T) {
+	var float64Value float64
+	var float32Value float32
+	clearEnvironment()
+	float64Option := HandlerConfigOption{
+		Argument: "float64-arg",
+		Default:  0.95,
+		Usage:    "Float64 argument",
+		Value:    &float64Value,
+	}
+	float32Option := HandlerConfigOption{
+		Argument: "float32-arg",
+		Default:  float32(0.5),
+		Usage:    "Float32 argument",
+		Value:    &float32Value,
+	}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, []*HandlerConfigOption{&float64Option, &float32Option},
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{"--float32-arg", "1.25"})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0.95, float64Value)
+	assert.Equal(t, float32(1.25), float32Value)
+}
+
+func TestGoHandler_Execute_Float64OptionEnvironment(t *testing.T) {
+	var float64Value float64
+	clearEnvironment()
+	_ = os.Setenv("ENV_1", "-3.14")
+	float64Option := HandlerConfigOption{
+		Argument: "float64-arg",
+		Env:      "ENV_1",
+		Default:  0.95,
+		Usage:    "Float64 argument",
+		Value:    &float64Value,
+	}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, []*HandlerConfigOption{&float64Option},
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+	clearEnvironment()
+
+	assert.Nil(t, err)
+	assert.Equal(t, -3.14, float64Value)
+}
+
+func TestGoHandler_Execute_DurationOption(t *testing.T) {
+	var durationValue time.Duration
+	clearEnvironment()
+	_ = os.Setenv("ENV_1", "1m30s")
+	durationOption := HandlerConfigOption{
+		Argument: "duration-arg",
+		Env:      "ENV_1",
+		Default:  10 * time.Second,
+		Usage:    "Duration argument",
+		Value:    &durationValue,
+	}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, []*HandlerConfigOption{&durationOption},
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+	clearEnvironment()
+
+	assert.Nil(t, err)
+	assert.Equal(t, 90*time.Second, durationValue)
+}
+
+func TestGoHandler_Execute_DurationOptionDefault(t *testing.T) {
+	var durationValue time.Duration
+	clearEnvironment()
+	durationOption := HandlerConfigOption{
+		Argument: "duration-arg",
+		Env:      "ENV_1",
+		Default:  -90 * time.Second,
+		Usage:    "Duration argument",
+		Value:    &durationValue,
+	}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, []*HandlerConfigOption{&durationOption},
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, -90*time.Second, durationValue)
+}
+
+func TestGoHandler_Execute_StringSliceOption(t *testing.T) {
+	var sliceValue []string
+	clearEnvironment()
+	sliceOption := HandlerConfigOption{
+		Argument: "slice-arg",
+		Env:      "ENV_1",
+		Default:  []string{"a", "b"},
+		Usage:    "String slice argument",
+		Value:    &sliceValue,
+	}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, []*HandlerConfigOption{&sliceOption},
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, sliceValue)
+	assert.Equal(t, []string{"ENV_1=a,b"}, goHandler.OptionsAsEnv())
+}
+
+func TestGoHandler_Execute_StringMapOption(t *testing.T) {
+	tests := []struct {
+		args          []string
+		expectedValue map[string]string
+		expectedErr   string
+	}{
+		{[]string{}, map[string]string{"Accept": "application/json"}, ""},
+		{[]string{"--headers", "X-Team = ops , Accept=text/plain,"}, map[string]string{"X-Team": "ops", "Accept": "text/plain"}, ""},
+		{[]string{"--headers", "Authorization=Bearer a=b"}, map[string]string{"Authorization": "Bearer a=b"}, ""},
+		{[]string{"--headers", "X-Team=ops,X-Team=dev"}, map[string]string{"X-Team": "dev"}, ""},
+		{[]string{"--headers", "X-Team=ops,Accept"}, nil,
+			"Error parsing X-Team=ops,Accept into a string map for option headers: entry \"Accept\" is missing '='"},
+	}
+
+	for _, test := range tests {
+		var headers map[string]string
+		clearEnvironment()
+		headersOption := HandlerConfigOption{
+			Argument: "headers",
+			Env:      "ENV_1",
+			Default:  map[string]string{"Accept": "application/json"},
+			Usage:    "HTTP headers",
+			Value:    &headers,
+		}
+
+		goHandler := NewGoHandler(&defaultHandlerConfig, []*HandlerConfigOption{&headersOption},
+			func(event *types.Event) error {
+				return nil
+			}, func(event *types.Event) error {
+				return nil
+			}, WithErrorWriter(ioutil.Discard))
+		goHandler.cmdArgs.SetArgs(test.args)
+		goHandler.eventReader = getFileReader("test/event-no-override.json")
+		err := goHandler.Execute()
+
+		if len(test.expectedErr) > 0 {
+			assert.EqualError(t, err, test.expectedErr)
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, test.expectedValue, headers)
+	}
+}
+
+func TestParseStringMap(t *testing.T) {
+	values, err := parseStringMap("")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{}, values)
+
+	values, err = parseStringMap("k1=v1, k2 = v2,k3=")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"k1": "v1", "k2": "v2", "k3": ""}, values)
+	assert.Equal(t, "k1=v1,k2=v2,k3=", formatStringMap(values))
+
+	_, err = parseStringMap("=v1")
+	assert.EqualError(t, err, "entry \"=v1\" has an empty key")
+}
+
+func TestGoHandler_Execute_CustomParser(t *testing.T) {
+	var ipValue, annotationIPValue net.IP
+	clearEnvironment()
+	_ = os.Setenv("ENV_1", "192.168.0.1")
+	ipOption := HandlerConfigOption{
+		Argument: "ip-arg",
+		Env:      "ENV_1",
+		Default:  "127.0.0.1",
+		Usage:    "IP argument",
+		Parse:    ipParser(&ipValue),
+	}
+	annotationIPOption := HandlerConfigOption{
+		Argument: "annotation-ip-arg",
+		Path:     "path1",
+		Usage:    "IP argument overridden by annotation",
+		Parse:    ipParser(&annotationIPValue),
+	}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, []*HandlerConfigOption{&ipOption, &annotationIPOption},
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-check-override.json")
+	err := goHandler.Execute()
+	clearEnvironment()
+
+	assert.EqualError(t, err, "Error parsing value-check1 for option annotation-ip-arg: invalid IP address")
+	assert.Equal(t, "192.168.0.1", ipValue.String())
+}
+
+func TestCheckOptions(t *testing.T) {
+	defer func(maxOptions int) {
+		MaxOptions = maxOptions
+	}(MaxOptions)
+	MaxOptions = 3
+	values := handlerValues{}
+	definedOptions := func() []*HandlerConfigOption {
+		options := getDefaultOptions()
+		options[0].Value = &values.arg1
+		options[1].Value = &values.arg2
+		options[2].Value = &values.arg3
+		return options
+	}
+
+	assert.Nil(t, CheckOptions(definedOptions()))
+
+	options := definedOptions()
+	options = append(options, options[0])
+	assert.EqualError(t, CheckOptions(options), "4 options defined, the maximum is 3")
+
+	options = definedOptions()
+	options[2].Argument = "arg1"
+	assert.EqualError(t, CheckOptions(options), "duplicate argument 'arg1' for options 1 and 3")
+
+	options = definedOptions()
+	options[1].Argument = "config"
+	assert.EqualError(t, CheckOptions(options), "argument 'config' of option 2 is reserved for the built-in --config flag")
+
+	options = definedOptions()
+	options[0].Pattern = "^[a-z"
+	assert.EqualError(t, CheckOptions(options),
+		"invalid pattern \"^[a-z\" for option arg1: error parsing regexp: missing closing ]: `[a-z`")
+
+	options = definedOptions()
+	options[1].Value = nil
+	assert.EqualError(t, CheckOptions(options), "Option value must not be nil for option arg2")
+
+	options = definedOptions()
+	options[1].Default = "33333"
+	assert.EqualError(t, CheckOptions(options), "option arg2: default type string incompatible with value type *uint64")
+
+	// The Default of raw options is checked against the type Value points to
+	// and parsed
+	var ip net.IP
+	var headers map[string]string
+	var rules routingRules
+	rawOptions := func(ipDefault interface{}) []*HandlerConfigOption {
+		return []*HandlerConfigOption{
+			{Argument: "ip", Default: ipDefault, Value: &ip},
+			{Argument: "headers", Default: map[string]string{"X-Team": "ops"}, Value: &headers},
+			{Argument: "rules", Default: routingRules{Default: "email"}, Value: &rules},
+		}
+	}
+	assert.Nil(t, CheckOptions(rawOptions(net.ParseIP("10.0.0.1"))))
+	assert.Nil(t, CheckOptions(rawOptions("10.0.0.1")))
+	assert.Nil(t, CheckOptions(rawOptions("")))
+	assert.EqualError(t, CheckOptions(rawOptions(5)), "option ip: default type int incompatible with value type *net.IP")
+	assert.EqualError(t, CheckOptions(rawOptions("10.0.0")),
+		"option ip: invalid default: Error parsing 10.0.0 into an IP address for option ip")
+	assert.Nil(t, ip)
+}
+
+func TestGoHandler_Execute_MaxOptions(t *testing.T) {
+	defer func(maxOptions int) {
+		MaxOptions = maxOptions
+	}(MaxOptions)
+	MaxOptions = 3
+	clearEnvironment()
+
+	for _, optionCount := range []int{3, 4} {
+		options := make([]*HandlerConfigOption, optionCount)
+		for i := range options {
+			value := ""
+			options[i] = &HandlerConfigOption{
+				Argument: fmt.Sprintf("arg%d", i),
+				Default:  "",
+				Value:    &value,
+			}
+		}
+
+		goHandler := NewGoHandler(&defaultHandlerConfig, options,
+			func(event *types.Event) error {
+				return nil
+			}, func(event *types.Event) error {
+				return nil
+			})
+		goHandler.cmdArgs.SetArgs([]string{})
+		goHandler.eventReader = getFileReader("test/event-no-override.json")
+		err := goHandler.Execute()
+
+		if optionCount <= MaxOptions {
+			assert.Nil(t, err)
+		} else {
+			assert.EqualError(t, err, "4 options defined, the maximum is 3")
+		}
+	}
+}
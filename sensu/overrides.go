@@ -0,0 +1,249 @@
+package sensu
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/sensu/sensu-go/types"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// KeyspaceEnv is the environment variable replacing the Keyspace of the
+// HandlerConfig at runtime, the --keyspace flag has priority over it
+const KeyspaceEnv = "SENSU_PLUGIN_KEYSPACE"
+
+// overrideKeyspace returns config with its Keyspace replaced by keyspace, read
+// from the --keyspace flag, or by the KeyspaceEnv environment variable when
+// either is set. The config is copied, leaving the plugin's own untouched.
+func overrideKeyspace(config *HandlerConfig, keyspace string) *HandlerConfig {
+	if len(keyspace) == 0 {
+		keyspace = os.Getenv(KeyspaceEnv)
+	}
+	if len(keyspace) == 0 || keyspace == config.Keyspace {
+		return config
+	}
+
+	overridden := *config
+	overridden.Keyspace = keyspace
+	return &overridden
+}
+
+func configurationOverrides(config *HandlerConfig, options []*HandlerConfigOption, event *types.Event,
+	errorLog *log.Logger) error {
+	// The check and entity are optional when their validation is skipped
+	var checkMeta, entityMeta types.ObjectMeta
+	if event.Check != nil {
+		checkMeta = event.Check.ObjectMeta
+	}
+	if event.Entity != nil {
+		entityMeta = event.Entity.ObjectMeta
+	}
+
+	// Layers of overrides, by decreasing priority
+	layers := []struct {
+		name    string
+		source  string
+		values  map[string]string
+		enabled bool
+	}{
+		{"Check.Annotations", sourceCheck, checkMeta.Annotations, true},
+		{"Check.Labels", sourceCheck, checkMeta.Labels, config.UseLabels},
+		{"Entity.Annotations", sourceEntity, entityMeta.Annotations, true},
+		{"Entity.Labels", sourceEntity, entityMeta.Labels, config.UseLabels},
+	}
+
+	if config.StrictKeyspace {
+		var unknownKeys []string
+		for _, layer := range layers {
+			if !layer.enabled {
+				continue
+			}
+			for _, key := range unknownKeyspaceKeys(config, options, layer.values) {
+				unknownKeys = append(unknownKeys, layer.name+"."+key)
+			}
+		}
+		if len(unknownKeys) > 0 {
+			return fmt.Errorf("unknown keys under the keyspace: %s", strings.Join(unknownKeys, ", "))
+		}
+	}
+
+	for _, opt := range options {
+		// compile the Annotation key to look for configuration overrides
+		k := annotationKey(config, opt)
+		if len(k) == 0 {
+			continue
+		}
+
+		overridden := false
+		for _, layer := range layers {
+			if !layer.enabled {
+				continue
+			}
+			value, ok := optionOverrideValue(layer.values, config, opt)
+			if !ok {
+				continue
+			}
+
+			err := setOptionValue(opt, value)
+			if err != nil {
+				return err
+			}
+			opt.source = layer.source
+			errorLog.Printf("Overriding default handler configuration with value of \"%s.%s\" (\"%s\")\n", layer.name, k, displayValue(opt, value))
+			overridden = true
+			break
+		}
+
+		// Namespace-wide values rank below the command line
+		if !overridden && config.NamespaceLookup != nil && opt.source != sourceCmdLine {
+			namespace := entityMeta.Namespace
+			if len(namespace) == 0 {
+				namespace = checkMeta.Namespace
+			}
+			value, ok := config.NamespaceLookup(namespace, k)
+			if !ok || len(value) == 0 {
+				continue
+			}
+
+			err := setOptionValue(opt, value)
+			if err != nil {
+				return err
+			}
+			opt.source = sourceNamespace
+			errorLog.Printf("Overriding default handler configuration with value of \"Namespace(%s).%s\" (\"%s\")\n", namespace, k, displayValue(opt, value))
+		}
+	}
+	return nil
+}
+
+// unknownKeyspaceKeys returns, sorted, the keys of values under the keyspace of
+// the handler or of an option that no option reads
+func unknownKeyspaceKeys(config *HandlerConfig, options []*HandlerConfigOption, values map[string]string) []string {
+	keyspaces := map[string]bool{}
+	if len(config.Keyspace) > 0 {
+		keyspaces[config.Keyspace] = true
+	}
+	knownKeys := map[string]bool{}
+	for _, opt := range options {
+		if len(opt.FullAnnotationKey) > 0 {
+			knownKeys[opt.FullAnnotationKey] = true
+			continue
+		}
+		keyspace := optionKeyspace(config, opt)
+		if len(opt.Path) == 0 || len(keyspace) == 0 {
+			continue
+		}
+		keyspaces[keyspace] = true
+
+		// A dotted path can also be read from the JSON object named by any of
+		// its leading parts
+		for optionPath := opt.Path; len(optionPath) > 0; {
+			knownKeys[path.Join(keyspace, optionPath)] = true
+			split := strings.LastIndex(optionPath, ".")
+			if split < 0 {
+				split = 0
+			}
+			optionPath = optionPath[:split]
+		}
+	}
+
+	var unknownKeys []string
+	for key := range values {
+		if knownKeys[key] {
+			continue
+		}
+		for keyspace := range keyspaces {
+			if strings.HasPrefix(key, keyspace+"/") {
+				unknownKeys = append(unknownKeys, key)
+				break
+			}
+		}
+	}
+	sort.Strings(unknownKeys)
+	return unknownKeys
+}
+
+// optionOverrideValue returns the value of the annotation or label of the
+// option, read from its FullAnnotationKey when set
+func optionOverrideValue(values map[string]string, config *HandlerConfig, option *HandlerConfigOption) (string, bool) {
+	if len(option.FullAnnotationKey) > 0 {
+		value := values[option.FullAnnotationKey]
+		return value, len(value) > 0
+	}
+	return overrideValue(values, optionKeyspace(config, option), option.Path)
+}
+
+// overrideValue returns the value of the annotation or label of the option
+// path. When it is not set and the path contains dots, such as smtp.host, the
+// leading part of the path can name an annotation or label holding a JSON
+// object the rest of the path is looked up in.
+func overrideValue(values map[string]string, keyspace string, optionPath string) (string, bool) {
+	if value := values[path.Join(keyspace, optionPath)]; len(value) > 0 {
+		return value, true
+	}
+
+	// Try the longest annotation names first
+	for split := strings.LastIndex(optionPath, "."); split > 0; split = strings.LastIndex(optionPath[:split], ".") {
+		objectJSON := values[path.Join(keyspace, optionPath[:split])]
+		if len(objectJSON) == 0 {
+			continue
+		}
+		if value, ok := nestedValue(objectJSON, strings.Split(optionPath[split+1:], ".")); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// nestedValue returns the value found following the keys in a JSON object,
+// false if the value is not a JSON object or the keys are missing
+func nestedValue(objectJSON string, keys []string) (string, bool) {
+	var value interface{}
+	decoder := json.NewDecoder(strings.NewReader(objectJSON))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return "", false
+	}
+
+	for _, key := range keys {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if value, ok = object[key]; !ok || value == nil {
+			return "", false
+		}
+	}
+
+	stringValue, err := configValueString(value)
+	if err != nil || len(stringValue) == 0 {
+		return "", false
+	}
+	return stringValue, true
+}
+
+// optionKeyspace returns the keyspace of the option's annotations and labels,
+// its own Keyspace if set or the handler Keyspace otherwise
+func optionKeyspace(config *HandlerConfig, option *HandlerConfigOption) string {
+	if len(option.Keyspace) > 0 {
+		return option.Keyspace
+	}
+	return config.Keyspace
+}
+
+// annotationKey returns the annotation and label key of the option, its
+// FullAnnotationKey if set or its Path in its keyspace otherwise. It is empty
+// when the option cannot be overridden.
+func annotationKey(config *HandlerConfig, option *HandlerConfigOption) string {
+	if len(option.FullAnnotationKey) > 0 {
+		return option.FullAnnotationKey
+	}
+	keyspace := optionKeyspace(config, option)
+	if len(option.Path) == 0 || len(keyspace) == 0 {
+		return ""
+	}
+	return path.Join(keyspace, option.Path)
+}
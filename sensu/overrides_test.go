@@ -0,0 +1,616 @@
+package sensu
+
+import (
+	"bytes"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestGoHandler_Execute_InvalidJSONOverride(t *testing.T) {
+	var rules routingRules
+	clearEnvironment()
+	options := []*HandlerConfigOption{{
+		Path:     "rules",
+		Argument: "rules",
+		Default:  "",
+		Usage:    "Routing rules",
+		Value:    &rules,
+	}}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{"--rules", "not-json"})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Error parsing not-json into JSON for option rules: ")
+}
+
+// Test nested paths reading a field of a JSON annotation
+func TestGoHandler_Execute_NestedPath(t *testing.T) {
+	var host, user, from string
+	var port uint64
+	var tlsEnabled bool
+	clearEnvironment()
+	options := []*HandlerConfigOption{
+		{Argument: "host", Path: "smtp.host", Value: &host, Default: "localhost"},
+		{Argument: "port", Path: "smtp.port", Value: &port, Default: uint64(25)},
+		{Argument: "tls", Path: "smtp.tls.enabled", Value: &tlsEnabled, Default: false},
+		{Argument: "user", Path: "smtp.user", Value: &user, Default: ""},
+		{Argument: "from", Path: "mail.from", Value: &from, Default: "sensu@example.com"},
+	}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) error {
+		return nil
+	})
+	goHandler.cmdArgs.SetArgs([]string{"--from", "alerts@example.com"})
+	goHandler.eventReader = getFileReader("test/event-nested-override.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	// Found in the check annotation
+	assert.Equal(t, "smtp.example.com", host)
+	assert.Equal(t, sourceCheck, options[0].source)
+	assert.Equal(t, uint64(2525), port)
+	assert.True(t, tlsEnabled)
+	// Missing from the check annotation, found in the entity annotation
+	assert.Equal(t, "entity-user", user)
+	assert.Equal(t, sourceEntity, options[3].source)
+	// The entity annotation is not JSON
+	assert.Equal(t, "alerts@example.com", from)
+	assert.Equal(t, sourceCmdLine, options[4].source)
+}
+
+// Test two-level nested paths, a missing intermediate key falls back to the
+// next source
+func TestGoHandler_Execute_NestedPathTwoLevels(t *testing.T) {
+	var cpu, disk, memory float64
+	clearEnvironment()
+	options := []*HandlerConfigOption{
+		{Argument: "cpu", Path: "config.thresholds.cpu", Value: &cpu, Default: float64(95)},
+		{Argument: "disk", Path: "config.thresholds.disk", Value: &disk, Default: float64(95)},
+		{Argument: "memory", Path: "config.limits.memory", Value: &memory, Default: float64(95)},
+	}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) error {
+		return nil
+	})
+	goHandler.cmdArgs.SetArgs([]string{"--disk", "70"})
+	goHandler.eventReader = getFileReader("test/event-nested-thresholds.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, 90.5, cpu)
+	assert.Equal(t, sourceCheck, options[0].source)
+	assert.Equal(t, float64(80), disk)
+	assert.Equal(t, sourceCheck, options[1].source)
+	// The limits key is missing from the annotation
+	assert.Equal(t, float64(95), memory)
+	assert.Equal(t, sourceDefault, options[2].source)
+}
+
+// Test strict mode rejecting the keys under the keyspace no option reads
+func TestGoHandler_Execute_StrictKeyspace(t *testing.T) {
+	tests := []struct {
+		eventFile   string
+		strict      bool
+		expectedErr string
+	}{
+		{"test/event-stray-annotation.json", false, ""},
+		{"test/event-stray-annotation.json", true, "unknown keys under the keyspace: " +
+			"Check.Annotations.sensu.io/plugins/segp/config/path_2, " +
+			"Entity.Annotations.sensu.io/plugins/segp/config/pth3"},
+		{"test/event-check-override.json", true, ""},
+	}
+
+	for _, test := range tests {
+		var executeCalled bool
+		clearEnvironment()
+		handlerConfig := defaultHandlerConfig
+		handlerConfig.StrictKeyspace = test.strict
+		options := getDefaultOptions()
+		values := handlerValues{}
+		options[0].Value = &values.arg1
+		options[1].Value = &values.arg2
+		options[2].Value = &values.arg3
+
+		goHandler := NewGoHandler(&handlerConfig, options,
+			func(event *types.Event) error {
+				return nil
+			}, func(event *types.Event) error {
+				executeCalled = true
+				return nil
+			})
+		goHandler.cmdArgs.SetArgs([]string{})
+		goHandler.eventReader = getFileReader(test.eventFile)
+		err := goHandler.Execute()
+
+		if len(test.expectedErr) > 0 {
+			assert.EqualError(t, err, test.expectedErr)
+			assert.False(t, executeCalled)
+		} else {
+			assert.Nil(t, err)
+			assert.True(t, executeCalled)
+		}
+	}
+}
+
+func TestUnknownKeyspaceKeys(t *testing.T) {
+	options := []*HandlerConfigOption{
+		{Path: "smtp.host"},
+		{Path: "token", Keyspace: "example.com/org/config"},
+		{FullAnnotationKey: "legacy_key"},
+	}
+	values := map[string]string{
+		"ks/smtp.host":                  "host",
+		"ks/smtp":                       `{"host": "host"}`,
+		"ks/smtp.port":                  "25",
+		"ks/smtpx":                      "typo",
+		"example.com/org/config/token":  "token",
+		"example.com/org/config/tokens": "typo",
+		"example.com/org/other":         "other",
+		"legacy_key":                    "value",
+		"ks":                            "not under the keyspace",
+	}
+
+	unknownKeys := unknownKeyspaceKeys(&HandlerConfig{Keyspace: "ks"}, options, values)
+	assert.Equal(t, []string{"example.com/org/config/tokens", "ks/smtp.port", "ks/smtpx"}, unknownKeys)
+}
+
+func TestOverrideValue(t *testing.T) {
+	values := map[string]string{
+		"ks/smtp":      `{"host": "smtp.example.com", "tls": {"enabled": true}, "ports": [25, 587], "empty": null}`,
+		"ks/smtp.user": "flat-user",
+		"ks/a.b":       `{"c": "from a.b"}`,
+		"ks/a":         `{"b": {"c": "from a"}}`,
+		"ks/list":      `["a", "b"]`,
+	}
+	tests := []struct {
+		path     string
+		expected string
+		found    bool
+	}{
+		{"smtp.host", "smtp.example.com", true},
+		{"smtp.tls", `{"enabled":true}`, true},
+		{"smtp.tls.enabled", "true", true},
+		{"smtp.ports", "25,587", true},
+		{"smtp.user", "flat-user", true},
+		{"a.b.c", "from a.b", true},
+		{"smtp.password", "", false},
+		{"smtp.empty", "", false},
+		{"smtp.host.name", "", false},
+		{"list.0", "", false},
+		{"missing.host", "", false},
+		{"smtp", `{"host": "smtp.example.com", "tls": {"enabled": true}, "ports": [25, 587], "empty": null}`, true},
+	}
+
+	for _, test := range tests {
+		value, found := overrideValue(values, "ks", test.path)
+		assert.Equal(t, test.found, found, test.path)
+		assert.Equal(t, test.expected, value, test.path)
+	}
+}
+
+// Test check override
+func TestGoHandler_Execute_Check(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-check-override.json", nil,
+		func(event *types.Event) error {
+			validateCalled = true
+			assert.NotNil(t, event)
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			assert.NotNil(t, event)
+			return nil
+		},
+		"value-check1", uint64(1357), false)
+	assert.Nil(t, err)
+	assert.True(t, validateCalled)
+	assert.True(t, executeCalled)
+}
+
+// Test check override with invalid value
+func TestGoHandler_Execute_CheckInvalidValue(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-check-override-invalid-value.json", nil,
+		func(event *types.Event) error {
+			validateCalled = true
+			assert.NotNil(t, event)
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			assert.NotNil(t, event)
+			return nil
+		},
+		"value-check1", uint64(33333), false)
+	assert.NotNil(t, err)
+	assert.False(t, validateCalled)
+	assert.False(t, executeCalled)
+}
+
+// Test entity override
+func TestGoHandler_Execute_Entity(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-entity-override.json", nil,
+		func(event *types.Event) error {
+			validateCalled = true
+			assert.NotNil(t, event)
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			assert.NotNil(t, event)
+			return nil
+		},
+		"value-entity1", uint64(2468), true)
+
+	assert.Nil(t, err)
+	assert.True(t, validateCalled)
+	assert.True(t, executeCalled)
+}
+
+// Test entity override - invalid value
+func TestGoHandler_Execute_EntityInvalidValue(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-entity-override-invalid-value.json", nil,
+		func(event *types.Event) error {
+			validateCalled = true
+			assert.NotNil(t, event)
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			assert.NotNil(t, event)
+			return nil
+		},
+		"value-entity1", uint64(33333), false)
+
+	assert.NotNil(t, err)
+	assert.False(t, validateCalled)
+	assert.False(t, executeCalled)
+}
+
+func TestGoHandler_Execute_PriorityCheck(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	_ = os.Setenv("ENV_1", "value-env1")
+	_ = os.Setenv("ENV_2", "9753")
+	_ = os.Setenv("ENV_3", "true")
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-check-entity-override.json", defaultCmdLineArgs,
+		func(event *types.Event) error {
+			validateCalled = true
+			assert.NotNil(t, event)
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			assert.NotNil(t, event)
+			return nil
+		},
+		"value-check1", uint64(1357), false)
+	assert.Nil(t, err)
+	assert.True(t, validateCalled)
+	assert.True(t, executeCalled)
+}
+
+// Test next priority - entity override
+func TestGoHandler_Execute_PriorityEntity(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	_ = os.Setenv("ENV_1", "value-env1")
+	_ = os.Setenv("ENV_2", "9753")
+	_ = os.Setenv("ENV_3", "true")
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-entity-override.json", defaultCmdLineArgs,
+		func(event *types.Event) error {
+			validateCalled = true
+			assert.NotNil(t, event)
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			assert.NotNil(t, event)
+			return nil
+		},
+		"value-entity1", uint64(2468), true)
+	assert.Nil(t, err)
+	assert.True(t, validateCalled)
+	assert.True(t, executeCalled)
+}
+
+// Test next priority - cmd line arguments
+func TestGoHandler_Execute_PriorityCmdLine(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	_ = os.Setenv("ENV_1", "value-env1")
+	_ = os.Setenv("ENV_2", "9753")
+	_ = os.Setenv("ENV_3", "true")
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-no-override.json", defaultCmdLineArgs,
+		func(event *types.Event) error {
+			validateCalled = true
+			assert.NotNil(t, event)
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			assert.NotNil(t, event)
+			return nil
+		},
+		"value-arg1", uint64(7531), false)
+	assert.Nil(t, err)
+	assert.True(t, validateCalled)
+	assert.True(t, executeCalled)
+}
+
+// Test namespace-wide values rank below the entity overrides and the command
+// line, and above the environment
+func TestGoHandler_Execute_PriorityNamespace(t *testing.T) {
+	var lookups []string
+	clearEnvironment()
+	_ = os.Setenv("ENV_1", "value-env1")
+	_ = os.Setenv("ENV_2", "9753")
+	handlerConfig := defaultHandlerConfig
+	handlerConfig.NamespaceLookup = func(namespace, key string) (string, bool) {
+		lookups = append(lookups, namespace+" "+key)
+		if namespace != "default" {
+			return "", false
+		}
+		switch key {
+		case "sensu.io/plugins/segp/config/path1":
+			return "value-namespace1", true
+		case "sensu.io/plugins/segp/config/path2":
+			return "8642", true
+		}
+		return "", false
+	}
+
+	err := goHandlerExecuteUtil(t, &handlerConfig, "test/event-no-override.json", []string{},
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		},
+		"value-namespace1", uint64(8642), false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"default sensu.io/plugins/segp/config/path1",
+		"default sensu.io/plugins/segp/config/path2",
+		"default sensu.io/plugins/segp/config/path3",
+	}, lookups)
+
+	// The command line arguments are not overridden by the namespace
+	lookups = nil
+	err = goHandlerExecuteUtil(t, &handlerConfig, "test/event-no-override.json", []string{"--arg1", "value-arg1"},
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		},
+		"value-arg1", uint64(8642), false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"default sensu.io/plugins/segp/config/path2",
+		"default sensu.io/plugins/segp/config/path3",
+	}, lookups)
+
+	// The entity overrides every option, the namespace is not consulted
+	lookups = nil
+	err = goHandlerExecuteUtil(t, &handlerConfig, "test/event-entity-override.json", defaultCmdLineArgs,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		},
+		"value-entity1", uint64(2468), true)
+	assert.Nil(t, err)
+	assert.Empty(t, lookups)
+}
+
+// Test label overrides
+func TestGoHandler_Execute_Labels(t *testing.T) {
+	handlerConfig := defaultHandlerConfig
+	handlerConfig.UseLabels = true
+	tests := []struct {
+		eventFile      string
+		expectedValue1 string
+		expectedValue2 uint64
+		expectedValue3 bool
+	}{
+		{"test/event-check-label-override.json", "label-check1", uint64(1122), true},
+		{"test/event-entity-label-override.json", "label-entity1", uint64(3344), true},
+		{"test/event-annotation-label-override.json", "value-check1", uint64(2468), true},
+		{"test/event-check-entity-override.json", "value-check1", uint64(1357), false},
+	}
+
+	for _, test := range tests {
+		clearEnvironment()
+		err := goHandlerExecuteUtil(t, &handlerConfig, test.eventFile, nil,
+			func(event *types.Event) error {
+				return nil
+			}, func(event *types.Event) error {
+				return nil
+			},
+			test.expectedValue1, test.expectedValue2, test.expectedValue3)
+		assert.Nil(t, err)
+	}
+}
+
+// Test labels are ignored unless enabled
+func TestGoHandler_Execute_LabelsDisabled(t *testing.T) {
+	clearEnvironment()
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-check-label-override.json", nil,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		},
+		"Default1", uint64(33333), false)
+	assert.Nil(t, err)
+}
+
+// Test no keyspace
+func TestGoHandler_Execute_NoKeyspace(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	handlerConfig := defaultHandlerConfig
+	handlerConfig.Keyspace = ""
+	err := goHandlerExecuteUtil(t, &handlerConfig, "test/event-check-entity-override.json", defaultCmdLineArgs,
+		func(event *types.Event) error {
+			validateCalled = true
+			assert.NotNil(t, event)
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			assert.NotNil(t, event)
+			return nil
+		},
+		"value-arg1", uint64(7531), false)
+	assert.Nil(t, err)
+	assert.True(t, validateCalled)
+	assert.True(t, executeCalled)
+}
+
+// Test options reading their annotations from their own keyspace
+func TestGoHandler_Execute_OptionKeyspace(t *testing.T) {
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[1].Keyspace = "example.com/org/config"
+	options[2].Value = &values.arg3
+	options[2].Keyspace = "example.com/org/config"
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-split-keyspace-override.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "value-check1", values.arg1)
+	assert.Equal(t, uint64(4321), values.arg2)
+	assert.Equal(t, sourceCheck, options[1].source)
+	assert.Equal(t, true, values.arg3)
+	assert.Equal(t, sourceEntity, options[2].source)
+}
+
+func TestGoHandler_Execute_KeyspaceOverride(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		args     []string
+		expected handlerValues
+	}{
+		{"compiled keyspace", "", []string{}, handlerValues{"value-check1", 1357, false}},
+		{"environment", "example.com/org/config", []string{}, handlerValues{"Default1", 4321, true}},
+		{"flag", "", []string{"--keyspace", "example.com/org/config"}, handlerValues{"Default1", 4321, true}},
+		{"flag over environment", "example.com/other/config", []string{"--keyspace", "example.com/org/config"},
+			handlerValues{"Default1", 4321, true}},
+	}
+
+	for _, test := range tests {
+		clearEnvironment()
+		_ = os.Setenv(KeyspaceEnv, test.env)
+		options := getDefaultOptions()
+		values := handlerValues{}
+		options[0].Value = &values.arg1
+		options[1].Value = &values.arg2
+		options[2].Value = &values.arg3
+
+		goHandler := NewGoHandler(&defaultHandlerConfig, options,
+			func(event *types.Event) error {
+				return nil
+			}, func(event *types.Event) error {
+				return nil
+			})
+		goHandler.cmdArgs.SetArgs(test.args)
+		goHandler.eventReader = getFileReader("test/event-split-keyspace-override.json")
+		err := goHandler.Execute()
+		_ = os.Unsetenv(KeyspaceEnv)
+
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.expected, values, test.name)
+		// The plugin configuration is left untouched
+		assert.Equal(t, "sensu.io/plugins/segp/config", defaultHandlerConfig.Keyspace, test.name)
+	}
+}
+
+func TestGoHandler_Annotations_KeyspaceOverride(t *testing.T) {
+	var output bytes.Buffer
+	_ = os.Setenv(KeyspaceEnv, "example.com/org/config")
+	defer os.Unsetenv(KeyspaceEnv)
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		}, WithOutputWriter(&output))
+	goHandler.cmdArgs.SetArgs([]string{"annotations"})
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Contains(t, output.String(), "example.com/org/config/path1")
+	assert.NotContains(t, output.String(), "sensu.io/plugins/segp/config")
+}
+
+func TestOverrideKeyspace(t *testing.T) {
+	_ = os.Unsetenv(KeyspaceEnv)
+	assert.Equal(t, &defaultHandlerConfig, overrideKeyspace(&defaultHandlerConfig, ""))
+	assert.Equal(t, "example.com/flag", overrideKeyspace(&defaultHandlerConfig, "example.com/flag").Keyspace)
+
+	_ = os.Setenv(KeyspaceEnv, "example.com/env")
+	defer os.Unsetenv(KeyspaceEnv)
+	assert.Equal(t, "example.com/env", overrideKeyspace(&defaultHandlerConfig, "").Keyspace)
+	assert.Equal(t, "example.com/flag", overrideKeyspace(&defaultHandlerConfig, "example.com/flag").Keyspace)
+	assert.Equal(t, "sensu.io/plugins/segp/config", defaultHandlerConfig.Keyspace)
+}
+
+func TestGoHandler_Execute_FullAnnotationKey(t *testing.T) {
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[0].FullAnnotationKey = "legacy_handler_arg1"
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+	options[2].FullAnnotationKey = "legacy_handler_arg3"
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-full-annotation-key.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "value-legacy1", values.arg1)
+	assert.Equal(t, sourceCheck, options[0].source)
+	assert.Equal(t, uint64(1357), values.arg2)
+	assert.Equal(t, sourceCheck, options[1].source)
+	assert.Equal(t, true, values.arg3)
+	assert.Equal(t, sourceEntity, options[2].source)
+}
@@ -0,0 +1,184 @@
+package sensu
+
+import (
+	"context"
+	"github.com/sensu/sensu-enterprise-go-plugin/args"
+	"github.com/sensu/sensu-go/types"
+	"io"
+	"log"
+)
+
+// optionResolver resolves the options of a plugin from their defaults, the
+// environment, the --config file, the sources added with ResolveFrom, the
+// command line and the overrides of the event, then reads the secrets and
+// validates the input. The plugin types embed it so that their options are
+// resolved the same way.
+type optionResolver struct {
+	config              *HandlerConfig
+	options             []*HandlerConfigOption
+	cmdArgs             *args.Args
+	errorWriter         io.Writer
+	configFile          string
+	keyspace            string
+	customSources       []customSource
+	secretResolver      SecretResolver
+	secretCache         *secretCache
+	azureKeyVaultClient AzureKeyVaultClient
+}
+
+// newOptionResolver creates the resolver of options, writing its diagnostic
+// messages to stderr until the error writer is replaced
+func newOptionResolver(config *HandlerConfig, options []*HandlerConfigOption,
+	errorWriter io.Writer) optionResolver {
+	return optionResolver{
+		config:      config,
+		options:     options,
+		errorWriter: errorWriter,
+	}
+}
+
+// registerFlags adds the --config flag and, for the plugins reading an event,
+// the --keyspace flag to the command line
+func (resolver *optionResolver) registerFlags(cmdArgs *args.Args, withKeyspace bool) {
+	resolver.cmdArgs = cmdArgs
+	cmdArgs.PersistentStringVarP(&resolver.configFile, "config", "", "",
+		"Read option values from this YAML, TOML or INI file, keyed by option argument or path")
+	if withKeyspace {
+		cmdArgs.PersistentStringVarP(&resolver.keyspace, "keyspace", "", "",
+			"Read the annotations and labels from this keyspace instead of the compiled one")
+	}
+}
+
+// registerOptions adds the options to the command line
+func (resolver *optionResolver) registerOptions() error {
+	return setupOptions(resolver.cmdArgs, resolver.options, resolver.config.CaseInsensitiveEnv,
+		resolver.errorLog())
+}
+
+// errorLog returns the logger writing the diagnostic messages to the error
+// writer
+func (resolver *optionResolver) errorLog() *log.Logger {
+	return log.New(resolver.errorWriter, "", log.LstdFlags)
+}
+
+// loadFlags applies the --keyspace flag to the configuration and reads the
+// --config file, once the command line is parsed
+func (resolver *optionResolver) loadFlags() error {
+	resolver.config = overrideKeyspace(resolver.config, resolver.keyspace)
+	return resolver.loadConfigFile()
+}
+
+// resolveCommandLine records the source of each option and parses the values
+// read from the command line and the environment with the custom parsers
+func (resolver *optionResolver) resolveCommandLine() error {
+	err := resolveSources(resolver.cmdArgs, resolver.options)
+	if err != nil {
+		return err
+	}
+
+	// Parse the command line and environment values using the custom parsers
+	return parseCustomOptions(resolver.options)
+}
+
+// resolveEventOptions applies the custom sources and the overrides of event,
+// unless nil, to the options resolved from the command line and the
+// environment, then reads the secrets and checks the required options are set
+func (resolver *optionResolver) resolveEventOptions(event *types.Event) error {
+	// Apply the custom sources ranking below the event information
+	for _, precedence := range []SourcePrecedence{PrecedenceAboveDefault, PrecedenceAboveEnv, PrecedenceAboveCmdLine} {
+		if err := applyCustomSources(resolver.customSources, precedence, resolver.options, resolver.errorLog()); err != nil {
+			return err
+		}
+	}
+
+	// Override the configuration with the event information
+	if event != nil {
+		err := configurationOverrides(resolver.config, resolver.options, event, resolver.errorLog())
+		if err != nil {
+			return err
+		}
+	}
+
+	// Apply the custom sources ranking above the event information
+	for _, precedence := range []SourcePrecedence{PrecedenceAboveEntity, PrecedenceAboveCheck} {
+		if err := applyCustomSources(resolver.customSources, precedence, resolver.options, resolver.errorLog()); err != nil {
+			return err
+		}
+	}
+
+	// Read the secrets the options reference
+	err := resolveSecrets(resolver.cachedSecretResolver(), resolver.options)
+	if err != nil {
+		return err
+	}
+
+	// Read the secrets from the Azure Key Vault references
+	err = resolveAzureKeyVaultSecrets(resolver.azureKeyVaultClient, resolver.options)
+	if err != nil {
+		return err
+	}
+
+	// Make sure the required options were set
+	return requireOptions(resolver.options)
+}
+
+// validateInput checks the resolved option values and runs the validation
+// function, recovering from its panics. A validation function error is
+// returned as a ValidationError, unless the configuration is in warn mode.
+func (resolver *optionResolver) validateInput(ctx context.Context, event *types.Event,
+	validationFunction func(ctx context.Context, event *types.Event) error) error {
+	// Validate the resolved option values
+	err := validateOptions(resolver.options)
+	if err != nil {
+		return err
+	}
+
+	if validationFunction == nil {
+		return nil
+	}
+
+	// Validate input using validationFunction
+	return validationError(resolver.config, recoverHandler(ctx, event, validationFunction), resolver.errorLog())
+}
+
+// resolve runs the whole pipeline for event: it reads the --config file,
+// resolves the options and validates the input within the configured Timeout
+func (resolver *optionResolver) resolve(event *types.Event,
+	validationFunction func(ctx context.Context, event *types.Event) error) error {
+	ctx, cancel := timeoutContext(resolver.config.Timeout)
+	defer cancel()
+
+	err := resolver.loadFlags()
+	if err != nil {
+		return err
+	}
+
+	err = resolver.resolveCommandLine()
+	if err != nil {
+		return err
+	}
+
+	err = resolver.resolveEventOptions(event)
+	if err != nil {
+		return err
+	}
+
+	return resolver.validateInput(ctx, event, validationFunction)
+}
+
+// withContext adapts a validation function without context to validateInput
+func withContext(validationFunction func(event *types.Event) error) func(ctx context.Context, event *types.Event) error {
+	if validationFunction == nil {
+		return nil
+	}
+	return func(_ context.Context, event *types.Event) error {
+		return validationFunction(event)
+	}
+}
+
+// SetErrorWriter sets the writer receiving the diagnostic and error messages,
+// stderr by default.
+func (resolver *optionResolver) SetErrorWriter(writer io.Writer) {
+	resolver.errorWriter = writer
+	resolver.cmdArgs.SetOutput(writer)
+}
@@ -0,0 +1,111 @@
+package sensu
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// SetSecretResolver sets the resolver fetching the value of the Secret options,
+// instead of reading the environment variable they name.
+func (resolver *optionResolver) SetSecretResolver(secretResolver SecretResolver) {
+	resolver.secretResolver = secretResolver
+}
+
+// SetSecretCacheTTL keeps the secrets fetched by the SecretResolver for ttl,
+// keyed by reference, so that the events handled by Serve reuse them until they
+// expire. A zero ttl, the default, fetches the secrets for every event.
+func (resolver *optionResolver) SetSecretCacheTTL(ttl time.Duration) {
+	resolver.secretCache = nil
+	if ttl > 0 {
+		resolver.secretCache = &secretCache{
+			ttl:     ttl,
+			now:     time.Now,
+			entries: map[string]cachedSecret{},
+		}
+	}
+}
+
+// SecretResolver fetches the value of the secret a Secret option references.
+// By default the reference is the name of an environment variable, which is
+// how Sensu exposes the secrets of its secrets providers to handlers.
+type SecretResolver interface {
+	ResolveSecret(reference string) (string, error)
+}
+
+// resolveSecrets replaces the value of the Secret options, unless it is the
+// default or was read from the environment, by the secret it references. The
+// environment variable it names is read when resolver is nil.
+func resolveSecrets(resolver SecretResolver, options []*HandlerConfigOption) error {
+	for _, opt := range options {
+		valuePtr, ok := opt.Value.(*string)
+		if !opt.Secret || !ok || len(*valuePtr) == 0 || opt.source == sourceDefault || opt.source == sourceEnv {
+			continue
+		}
+
+		if resolver != nil {
+			secret, err := resolver.ResolveSecret(*valuePtr)
+			if err != nil {
+				return fmt.Errorf("failed to resolve secret for option %s: %s", opt.Argument, err)
+			}
+			*valuePtr = secret
+			continue
+		}
+
+		secret, ok := os.LookupEnv(*valuePtr)
+		if !ok {
+			return fmt.Errorf("environment variable %s referenced by option %s is not set", *valuePtr, opt.Argument)
+		}
+		*valuePtr = secret
+	}
+	return nil
+}
+
+// cachedSecret is a secret kept by a secretCache until it expires
+type cachedSecret struct {
+	value   string
+	expires time.Time
+}
+
+// secretCache keeps the secrets fetched by a SecretResolver for ttl
+type secretCache struct {
+	ttl     time.Duration
+	now     func() time.Time
+	mutex   sync.Mutex
+	entries map[string]cachedSecret
+}
+
+// cachingSecretResolver fetches the secrets with resolver unless cache holds an
+// entry that has not expired yet. Errors are not cached.
+type cachingSecretResolver struct {
+	cache    *secretCache
+	resolver SecretResolver
+}
+
+func (resolver cachingSecretResolver) ResolveSecret(reference string) (string, error) {
+	cache := resolver.cache
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	now := cache.now()
+	if entry, ok := cache.entries[reference]; ok && now.Before(entry.expires) {
+		return entry.value, nil
+	}
+
+	secret, err := resolver.resolver.ResolveSecret(reference)
+	if err != nil {
+		return "", err
+	}
+	cache.entries[reference] = cachedSecret{value: secret, expires: now.Add(cache.ttl)}
+	return secret, nil
+}
+
+// cachedSecretResolver returns the secret resolver, going through the secret
+// cache when SetSecretCacheTTL enabled it
+func (resolver *optionResolver) cachedSecretResolver() SecretResolver {
+	if resolver.secretResolver == nil || resolver.secretCache == nil {
+		return resolver.secretResolver
+	}
+	return cachingSecretResolver{cache: resolver.secretCache, resolver: resolver.secretResolver}
+}
@@ -0,0 +1,246 @@
+package sensu
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+// Test secret options read their value from the environment variable they name
+func TestGoHandler_Execute_Secret(t *testing.T) {
+	tests := []struct {
+		env           string
+		args          []string
+		eventFile     string
+		expectedValue string
+		expectedErr   string
+	}{
+		{"", []string{}, "test/event-no-override.json", "Default1", ""},
+		{"", []string{"--arg1", "SECRET_VALUE"}, "test/event-no-override.json", "s3cr3t", ""},
+		{"", []string{"--arg1", "MISSING_SECRET"}, "test/event-no-override.json", "MISSING_SECRET",
+			"environment variable MISSING_SECRET referenced by option arg1 is not set"},
+		{"value-env", []string{}, "test/event-no-override.json", "value-env", ""},
+		{"", []string{}, "test/event-check-override.json", "value-check1",
+			"environment variable value-check1 referenced by option arg1 is not set"},
+	}
+
+	for _, test := range tests {
+		clearEnvironment()
+		_ = os.Setenv("SECRET_VALUE", "s3cr3t")
+		if len(test.env) > 0 {
+			_ = os.Setenv("ENV_1", test.env)
+		}
+		options := getDefaultOptions()
+		values := handlerValues{}
+		options[0].Value = &values.arg1
+		options[0].Secret = true
+		options[1].Value = &values.arg2
+		options[2].Value = &values.arg3
+
+		goHandler := NewGoHandler(&defaultHandlerConfig, options,
+			func(event *types.Event) error {
+				return nil
+			}, func(event *types.Event) error {
+				return nil
+			})
+		goHandler.cmdArgs.SetArgs(test.args)
+		goHandler.eventReader = getFileReader(test.eventFile)
+		err := goHandler.Execute()
+		_ = os.Unsetenv("SECRET_VALUE")
+		_ = os.Unsetenv("ENV_1")
+
+		if len(test.expectedErr) > 0 {
+			assert.EqualError(t, err, test.expectedErr)
+		} else {
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, test.expectedValue, values.arg1)
+	}
+}
+
+// Test secret values never appear in the diagnostic output
+func TestGoHandler_Execute_SecretMasked(t *testing.T) {
+	var logOutput, jsonLogs bytes.Buffer
+	clearEnvironment()
+	_ = os.Setenv("SECRET_VALUE", "s3cr3t")
+
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[0].Secret = true
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.SetLogger(NewJSONLogger(&jsonLogs))
+	goHandler.SetErrorWriter(&logOutput)
+	goHandler.ResolveFrom(map[string]string{"path1": "SECRET_VALUE"}, "custom", PrecedenceAboveCheck)
+	goHandler.cmdArgs.SetArgs([]string{"--log-level", "debug"})
+	goHandler.eventReader = getFileReader("test/event-check-override.json")
+	err := goHandler.Execute()
+	_ = os.Unsetenv("SECRET_VALUE")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "s3cr3t", values.arg1)
+	assert.Equal(t, "****", goHandler.ResolvedValues()["arg1"])
+	assert.Equal(t, uint64(1357), goHandler.ResolvedValues()["arg2"])
+	assert.NotContains(t, logOutput.String(), "s3cr3t")
+	assert.NotContains(t, jsonLogs.String(), "s3cr3t")
+	assert.Contains(t, jsonLogs.String(), `"value":"****"`)
+	assert.Contains(t, jsonLogs.String(), `"value":1357`)
+}
+
+type fakeSecretResolver struct {
+	secrets map[string]string
+	calls   int
+}
+
+func (resolver *fakeSecretResolver) ResolveSecret(reference string) (string, error) {
+	resolver.calls++
+	if resolver.secrets == nil {
+		return "", fmt.Errorf("secrets backend unavailable")
+	}
+	secret, ok := resolver.secrets[reference]
+	if !ok {
+		return "", fmt.Errorf("secret %s not found", reference)
+	}
+	return secret, nil
+}
+
+// Test secrets fetched with a custom resolver
+func TestGoHandler_Execute_SecretResolver(t *testing.T) {
+	tests := []struct {
+		resolver      *fakeSecretResolver
+		args          []string
+		expectedValue string
+		expectedErr   string
+	}{
+		{&fakeSecretResolver{secrets: map[string]string{"pagerduty-token": "s3cr3t"}},
+			[]string{"--arg1", "pagerduty-token"}, "s3cr3t", ""},
+		{&fakeSecretResolver{secrets: map[string]string{}}, []string{"--arg1", "pagerduty-token"}, "pagerduty-token",
+			"failed to resolve secret for option arg1: secret pagerduty-token not found"},
+		{&fakeSecretResolver{}, []string{"--arg1", "pagerduty-token"}, "pagerduty-token",
+			"failed to resolve secret for option arg1: secrets backend unavailable"},
+		{&fakeSecretResolver{}, []string{}, "Default1", ""},
+	}
+
+	for _, test := range tests {
+		clearEnvironment()
+		options := getDefaultOptions()
+		values := handlerValues{}
+		options[0].Value = &values.arg1
+		options[0].Secret = true
+		options[1].Value = &values.arg2
+		options[2].Value = &values.arg3
+
+		goHandler := NewGoHandler(&defaultHandlerConfig, options,
+			func(event *types.Event) error {
+				return nil
+			}, func(event *types.Event) error {
+				return nil
+			})
+		goHandler.SetSecretResolver(test.resolver)
+		goHandler.cmdArgs.SetArgs(test.args)
+		goHandler.eventReader = getFileReader("test/event-no-override.json")
+		err := goHandler.Execute()
+
+		if len(test.expectedErr) > 0 {
+			assert.EqualError(t, err, test.expectedErr)
+		} else {
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, test.expectedValue, values.arg1)
+	}
+}
+
+// Test the cached secrets are fetched again once they expire
+func TestGoHandler_Execute_SecretCacheTTL(t *testing.T) {
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[0].Secret = true
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	secretResolver := &fakeSecretResolver{secrets: map[string]string{"pagerduty-token": "s3cr3t"}}
+	goHandler.SetSecretResolver(secretResolver)
+	goHandler.SetSecretCacheTTL(time.Minute)
+	now := time.Now()
+	goHandler.secretCache.now = func() time.Time {
+		return now
+	}
+
+	execute := func() {
+		goHandler.cmdArgs.SetArgs([]string{"--arg1", "pagerduty-token"})
+		goHandler.eventReader = getFileReader("test/event-no-override.json")
+		assert.Nil(t, goHandler.Execute())
+		assert.Equal(t, "s3cr3t", values.arg1)
+	}
+
+	execute()
+	assert.Equal(t, 1, secretResolver.calls)
+
+	// Within the TTL the cached secret is used
+	now = now.Add(59 * time.Second)
+	execute()
+	assert.Equal(t, 1, secretResolver.calls)
+
+	// Once expired the secret is fetched again
+	now = now.Add(time.Second)
+	execute()
+	assert.Equal(t, 2, secretResolver.calls)
+
+	// Without a TTL the secret is fetched for every event
+	goHandler.SetSecretCacheTTL(0)
+	execute()
+	assert.Equal(t, 3, secretResolver.calls)
+}
+
+// Test the errors of the secret resolver are not cached
+func TestCachingSecretResolver_Error(t *testing.T) {
+	secretResolver := &fakeSecretResolver{}
+	resolver := cachingSecretResolver{
+		cache:    &secretCache{ttl: time.Minute, now: time.Now, entries: map[string]cachedSecret{}},
+		resolver: secretResolver,
+	}
+
+	_, err := resolver.ResolveSecret("pagerduty-token")
+	assert.EqualError(t, err, "secrets backend unavailable")
+	secretResolver.secrets = map[string]string{"pagerduty-token": "s3cr3t"}
+	secret, err := resolver.ResolveSecret("pagerduty-token")
+	assert.Nil(t, err)
+	assert.Equal(t, "s3cr3t", secret)
+	assert.Equal(t, 2, secretResolver.calls)
+}
+
+// Test a secret referenced by an annotation
+func TestResolveSecrets_Annotation(t *testing.T) {
+	clearEnvironment()
+	_ = os.Setenv("value-check1", "from-annotation")
+	defer os.Unsetenv("value-check1")
+
+	value := "value-check1"
+	option := defaultOption1
+	option.Value = &value
+	option.Secret = true
+	option.source = sourceCheck
+
+	assert.Nil(t, resolveSecrets(nil, []*HandlerConfigOption{&option}))
+	assert.Equal(t, "from-annotation", value)
+}
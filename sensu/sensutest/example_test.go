@@ -0,0 +1,38 @@
+package sensutest_test
+
+import (
+	"fmt"
+	"github.com/sensu/sensu-enterprise-go-plugin/sensu"
+	"github.com/sensu/sensu-enterprise-go-plugin/sensu/sensutest"
+	"github.com/sensu/sensu-go/types"
+)
+
+func ExampleRunHandler() {
+	var webhook string
+	config := sensu.HandlerConfig{
+		Name:     "sensu-webhook-handler",
+		Short:    "Posts Sensu events to a webhook",
+		Keyspace: "sensu.io/plugins/webhook/config",
+	}
+	options := []*sensu.HandlerConfigOption{
+		{Argument: "webhook", Path: "webhook", Env: "WEBHOOK_URL", Default: "", Value: &webhook},
+	}
+	event := []byte(`{
+	  "timestamp": 1550816106,
+	  "entity": {"entity_class": "agent", "metadata": {"name": "webserver01", "namespace": "default"}},
+	  "check": {"interval": 20, "metadata": {"name": "check-nginx", "namespace": "default"}}
+	}`)
+
+	values, err := sensutest.RunHandler(&config, options, event,
+		[]string{"--webhook", "https://example.com/hook"},
+		sensutest.WithClearedEnv("WEBHOOK_URL"),
+		sensutest.WithExecute(func(event *types.Event) error {
+			fmt.Printf("posting %s to %s\n", event.Check.Name, webhook)
+			return nil
+		}))
+
+	fmt.Println(values["webhook"], err)
+	// Output:
+	// posting check-nginx to https://example.com/hook
+	// https://example.com/hook <nil>
+}
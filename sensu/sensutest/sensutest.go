@@ -0,0 +1,113 @@
+// Package sensutest provides helpers to test handlers built with the sensu
+// package, without the boilerplate of wiring the event, the command line and
+// the environment of every test by hand.
+package sensutest
+
+import (
+	"bytes"
+	"github.com/sensu/sensu-enterprise-go-plugin/sensu"
+	"github.com/sensu/sensu-go/types"
+	"os"
+)
+
+// Option configures a handler run by RunHandler
+type Option func(run *handlerRun)
+
+type handlerRun struct {
+	validationFunction func(event *types.Event) error
+	executeFunction    func(event *types.Event) error
+	clearEnv           []string
+	handlerOptions     []sensu.GoHandlerOption
+}
+
+// WithValidation sets the validation function of the handler, which accepts
+// every event by default.
+func WithValidation(validationFunction func(event *types.Event) error) Option {
+	return func(run *handlerRun) {
+		run.validationFunction = validationFunction
+	}
+}
+
+// WithExecute sets the execute function of the handler, which does nothing
+// by default.
+func WithExecute(executeFunction func(event *types.Event) error) Option {
+	return func(run *handlerRun) {
+		run.executeFunction = executeFunction
+	}
+}
+
+// WithClearedEnv unsets the environment variables keys while the handler
+// runs, so that the environment of the test does not leak into the option
+// values. They are restored once the handler returns.
+func WithClearedEnv(keys ...string) Option {
+	return func(run *handlerRun) {
+		run.clearEnv = append(run.clearEnv, keys...)
+	}
+}
+
+// WithHandlerOptions passes handlerOptions to the handler, for instance
+// sensu.WithOutputWriter to capture its output.
+func WithHandlerOptions(handlerOptions ...sensu.GoHandlerOption) Option {
+	return func(run *handlerRun) {
+		run.handlerOptions = append(run.handlerOptions, handlerOptions...)
+	}
+}
+
+// RunHandler creates a handler with config and options, runs it with the
+// command line arguments args against the event eventJSON and returns the
+// resolved option values, keyed by option argument, with the error returned
+// by Execute. A StatusError is returned instead of exiting the process.
+func RunHandler(config *sensu.HandlerConfig, options []*sensu.HandlerConfigOption, eventJSON []byte,
+	args []string, runOptions ...Option) (map[string]interface{}, error) {
+	run := &handlerRun{
+		validationFunction: func(event *types.Event) error {
+			return nil
+		},
+		executeFunction: func(event *types.Event) error {
+			return nil
+		},
+	}
+	for _, runOption := range runOptions {
+		runOption(run)
+	}
+
+	restoreEnv := clearEnv(run.clearEnv)
+	defer restoreEnv()
+
+	if args == nil {
+		args = []string{}
+	}
+	handlerOptions := append([]sensu.GoHandlerOption{
+		sensu.WithEventReader(bytes.NewReader(eventJSON)),
+		sensu.WithArgs(args),
+		sensu.WithExitFunction(func(status int) {}),
+	}, run.handlerOptions...)
+
+	goHandler := sensu.NewGoHandler(config, options, run.validationFunction, run.executeFunction,
+		handlerOptions...)
+	err := goHandler.Execute()
+
+	return goHandler.ResolvedValues(), err
+}
+
+// clearEnv unsets the environment variables keys and returns a function
+// restoring their values
+func clearEnv(keys []string) func() {
+	values := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if value, ok := os.LookupEnv(key); ok {
+			values[key] = value
+		}
+		_ = os.Unsetenv(key)
+	}
+
+	return func() {
+		for _, key := range keys {
+			if value, ok := values[key]; ok {
+				_ = os.Setenv(key, value)
+			} else {
+				_ = os.Unsetenv(key)
+			}
+		}
+	}
+}
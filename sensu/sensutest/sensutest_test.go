@@ -0,0 +1,101 @@
+package sensutest
+
+import (
+	"errors"
+	"github.com/sensu/sensu-enterprise-go-plugin/sensu"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+var testConfig = sensu.HandlerConfig{
+	Name:     "test-handler",
+	Short:    "Test handler",
+	Keyspace: "sensu.io/plugins/test/config",
+}
+
+const testEvent = `{
+  "timestamp": 1550816106,
+  "entity": {
+    "entity_class": "agent",
+    "metadata": {"name": "webserver01", "namespace": "default"}
+  },
+  "check": {
+    "interval": 20,
+    "metadata": {
+      "name": "check-nginx",
+      "namespace": "default",
+      "annotations": {"sensu.io/plugins/test/config/url": "https://example.com/hook"}
+    }
+  }
+}`
+
+func getTestOptions(url, channel *string) []*sensu.HandlerConfigOption {
+	return []*sensu.HandlerConfigOption{
+		{Argument: "url", Path: "url", Env: "SENSUTEST_URL", Default: "", Value: url},
+		{Argument: "channel", Shorthand: "c", Env: "SENSUTEST_CHANNEL", Default: "#alerts", Value: channel},
+	}
+}
+
+func TestRunHandler(t *testing.T) {
+	var url, channel string
+	var executedCheck string
+
+	values, err := RunHandler(&testConfig, getTestOptions(&url, &channel), []byte(testEvent),
+		[]string{"--channel", "#ops"}, WithExecute(func(event *types.Event) error {
+			executedCheck = event.Check.Name
+			return nil
+		}))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "check-nginx", executedCheck)
+	assert.Equal(t, "https://example.com/hook", url)
+	assert.Equal(t, map[string]interface{}{
+		"url":     "https://example.com/hook",
+		"channel": "#ops",
+	}, values)
+}
+
+func TestRunHandler_Errors(t *testing.T) {
+	var url, channel string
+	var executeCalled bool
+
+	_, err := RunHandler(&testConfig, getTestOptions(&url, &channel), []byte(testEvent), nil,
+		WithValidation(func(event *types.Event) error {
+			return errors.New("validation error")
+		}), WithExecute(func(event *types.Event) error {
+			executeCalled = true
+			return nil
+		}), WithHandlerOptions(sensu.WithErrorWriter(ioutil.Discard)))
+
+	var validationErr *sensu.ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.False(t, executeCalled)
+
+	// A StatusError is returned instead of exiting the test
+	_, err = RunHandler(&testConfig, getTestOptions(&url, &channel), []byte(testEvent), nil,
+		WithExecute(func(event *types.Event) error {
+			return &sensu.StatusError{Status: sensu.CheckStateCritical, Err: errors.New("critical")}
+		}), WithHandlerOptions(sensu.WithErrorWriter(ioutil.Discard)))
+
+	var statusErr *sensu.StatusError
+	assert.True(t, errors.As(err, &statusErr))
+}
+
+func TestRunHandler_ClearedEnv(t *testing.T) {
+	var url, channel string
+	defer os.Unsetenv("SENSUTEST_CHANNEL")
+	os.Setenv("SENSUTEST_CHANNEL", "#from-env")
+
+	values, err := RunHandler(&testConfig, getTestOptions(&url, &channel), []byte(testEvent), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "#from-env", values["channel"])
+
+	values, err = RunHandler(&testConfig, getTestOptions(&url, &channel), []byte(testEvent), nil,
+		WithClearedEnv("SENSUTEST_CHANNEL"))
+	assert.Nil(t, err)
+	assert.Equal(t, "#alerts", values["channel"])
+	assert.Equal(t, "#from-env", os.Getenv("SENSUTEST_CHANNEL"))
+}
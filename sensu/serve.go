@@ -0,0 +1,111 @@
+package sensu
+
+import (
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+// DefaultServePath is the path Serve accepts events on unless SetServePath is
+// called
+const DefaultServePath = "/events"
+
+// Serve parses the command line arguments, resolves the options from the
+// command line and the environment once, then starts an HTTP server on addr
+// running the handler for every event POSTed to the serve path. The event
+// overrides are applied to each event separately. It responds 200 when the
+// handler succeeds, 400 when the event or its configuration is invalid and
+// 500 when the execute function fails.
+func (goHandler *GoHandler) Serve(addr string) error {
+	goHandler.serveAddr = addr
+	return goHandler.Execute()
+}
+
+// SetServePath sets the path Serve accepts events on.
+func (goHandler *GoHandler) SetServePath(servePath string) {
+	goHandler.servePath = servePath
+}
+
+func (goHandler *GoHandler) serve() error {
+	err := goHandler.resolveCommandLine()
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(goHandler.servePath, goHandler.eventHandler(snapshotOptions(goHandler.options)))
+	goHandler.errorLog().Printf("Accepting events on %s%s\n", goHandler.serveAddr, goHandler.servePath)
+
+	return goHandler.listenFunction(goHandler.serveAddr, mux)
+}
+
+// eventHandler returns the HTTP handler running the handler for the POSTed
+// events. The options are restored before each event so that the overrides of
+// an event do not apply to the next one, and events are handled one at a time.
+func (goHandler *GoHandler) eventHandler(restoreOptions func()) http.Handler {
+	var mutex sync.Mutex
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodPost {
+			http.Error(writer, "events must be POSTed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		sensuEvent, err := readEvent(request.Body, goHandler.config, goHandler.inputFormat)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		restoreOptions()
+		goHandler.sensuEvent = sensuEvent
+
+		ctx, cancel := timeoutContext(goHandler.config.Timeout)
+		defer cancel()
+
+		if err = goHandler.resolveEventAndValidate(ctx); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err = goHandler.executeEvent(ctx); err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writer.WriteHeader(http.StatusOK)
+	})
+}
+
+// snapshotOptions saves the current option values and returns a function
+// restoring them
+func snapshotOptions(options []*HandlerConfigOption) func() {
+	type optionSnapshot struct {
+		value    reflect.Value
+		rawValue string
+		source   string
+	}
+
+	snapshots := make([]optionSnapshot, len(options))
+	for i, opt := range options {
+		snapshots[i].rawValue = opt.rawValue
+		snapshots[i].source = opt.source
+		if opt.Value != nil {
+			value := reflect.ValueOf(opt.Value).Elem()
+			snapshots[i].value = reflect.New(value.Type()).Elem()
+			snapshots[i].value.Set(value)
+		}
+	}
+
+	return func() {
+		for i, opt := range options {
+			opt.rawValue = snapshots[i].rawValue
+			opt.source = snapshots[i].source
+			if opt.Value != nil {
+				reflect.ValueOf(opt.Value).Elem().Set(snapshots[i].value)
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package sensu
+
+import (
+	"fmt"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGoHandler_Serve(t *testing.T) {
+	var executeValues []string
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			if event.Check.Name == "fail" {
+				return fmt.Errorf("execute error")
+			}
+			executeValues = append(executeValues, values.arg1)
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{"--arg1", "value-cmdline"})
+	var listenAddr string
+	var server *httptest.Server
+	goHandler.listenFunction = func(addr string, handler http.Handler) error {
+		listenAddr = addr
+		server = httptest.NewServer(handler)
+		return nil
+	}
+	err := goHandler.Serve(":8080")
+	assert.Nil(t, err)
+	assert.Equal(t, ":8080", listenAddr)
+	assert.NotNil(t, server)
+	defer server.Close()
+
+	post := func(fileName string) (int, string) {
+		file, err := os.Open(fileName)
+		assert.Nil(t, err)
+		defer file.Close()
+		response, err := http.Post(server.URL+DefaultServePath, "application/json", file)
+		assert.Nil(t, err)
+		defer response.Body.Close()
+		body, _ := ioutil.ReadAll(response.Body)
+		return response.StatusCode, string(body)
+	}
+
+	// The check override applies to its own event only
+	status, _ := post("test/event-check-override.json")
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = post("test/event-no-override.json")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"value-check1", "value-cmdline"}, executeValues)
+
+	status, _ = post("test/event-invalid-json.json")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, body := post("test/event-check-override-invalid-value.json")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body, "Error parsing abc into a uint64 for option arg2")
+
+	event, err := ioutil.ReadFile("test/event-no-override.json")
+	assert.Nil(t, err)
+	failingEvent := strings.Replace(string(event), `"name": "check-nginx"`, `"name": "fail"`, 1)
+	assert.NotEqual(t, string(event), failingEvent)
+	response, err := http.Post(server.URL+DefaultServePath, "application/json", strings.NewReader(failingEvent))
+	assert.Nil(t, err)
+	body2, _ := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
+	assert.Contains(t, string(body2), "error executing handler: execute error")
+
+	response, err = http.Get(server.URL + DefaultServePath)
+	assert.Nil(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, response.StatusCode)
+}
+
+func TestGoHandler_ServePath(t *testing.T) {
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+	goHandler := NewGoHandler(&defaultHandlerConfig, options, nil, nil)
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.SetServePath("/sensu")
+	var server *httptest.Server
+	goHandler.listenFunction = func(addr string, handler http.Handler) error {
+		server = httptest.NewServer(handler)
+		return nil
+	}
+	err := goHandler.Serve("localhost:0")
+	assert.Nil(t, err)
+	defer server.Close()
+
+	response, err := http.Post(server.URL+DefaultServePath, "application/json", strings.NewReader("{}"))
+	assert.Nil(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
+
+func TestGoHandler_Serve_ListenError(t *testing.T) {
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+	goHandler := NewGoHandler(&defaultHandlerConfig, options, nil, nil)
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.listenFunction = func(addr string, handler http.Handler) error {
+		return fmt.Errorf("address already in use")
+	}
+	err := goHandler.Serve(":8080")
+	assert.EqualError(t, err, "address already in use")
+}
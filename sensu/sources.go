@@ -0,0 +1,102 @@
+package sensu
+
+import (
+	"log"
+)
+
+// Sources an option value can be resolved from
+const (
+	sourceDefault   = "default"
+	sourceEnv       = "env"
+	sourceCmdLine   = "cmdline"
+	sourceEntity    = "entity"
+	sourceCheck     = "check"
+	sourceNamespace = "namespace"
+)
+
+// SourcePrecedence places a source added with ResolveFrom in the resolution
+// chain: its values override the built-in sources below it and are overridden
+// by the ones above it
+type SourcePrecedence int
+
+const (
+	PrecedenceAboveDefault SourcePrecedence = iota
+	PrecedenceAboveEnv
+	PrecedenceAboveCmdLine
+	PrecedenceAboveEntity
+	PrecedenceAboveCheck
+)
+
+// customSource is a key/value source added with ResolveFrom, or read from the
+// --config file
+type customSource struct {
+	name       string
+	values     map[string]string
+	precedence SourcePrecedence
+	// matchArgument looks the values up by option argument, then by path
+	matchArgument bool
+	// configFile marks the values read from the --config file
+	configFile bool
+}
+
+// value returns the value the source provides for the option and its key
+func (source customSource) value(opt *HandlerConfigOption) (string, string, bool) {
+	if source.matchArgument {
+		if value, ok := source.values[opt.Argument]; ok {
+			return value, opt.Argument, true
+		}
+	}
+	if len(opt.Path) == 0 {
+		return "", "", false
+	}
+	value, ok := source.values[opt.Path]
+	return value, opt.Path, ok
+}
+
+// ResolveFrom adds a source of option values, keyed by the option Path, to the
+// resolution chain at the given precedence. The source name is reported as
+// the source of the values it provides. Sources with the same precedence are
+// applied in the order they were added, the last one wins.
+func (resolver *optionResolver) ResolveFrom(values map[string]string, source string, precedence SourcePrecedence) {
+	resolver.customSources = append(resolver.customSources, customSource{
+		name:       source,
+		values:     values,
+		precedence: precedence,
+	})
+}
+
+// applyCustomSources sets the option values provided by the custom sources of
+// the given precedence, unless the option was resolved from a built-in source
+// with a higher precedence
+func applyCustomSources(sources []customSource, precedence SourcePrecedence, options []*HandlerConfigOption,
+	errorLog *log.Logger) error {
+	for _, source := range sources {
+		if source.precedence != precedence {
+			continue
+		}
+		for _, opt := range options {
+			value, key, ok := source.value(opt)
+			if !ok || len(value) == 0 || sourceRank(opt.source) > int(precedence) {
+				continue
+			}
+
+			if err := setOptionValue(opt, value); err != nil {
+				return err
+			}
+			opt.source = source.name
+			errorLog.Printf("Overriding default handler configuration with value of \"%s.%s\" (\"%s\")\n", source.name, key, displayValue(opt, value))
+		}
+	}
+	return nil
+}
+
+// sourceRank returns the position of a built-in source in the resolution chain,
+// matching the SourcePrecedence placed right above it, or -1 for custom sources
+func sourceRank(source string) int {
+	for rank, builtinSource := range []string{sourceDefault, sourceEnv, sourceCmdLine, sourceEntity, sourceCheck} {
+		if source == builtinSource {
+			return rank
+		}
+	}
+	return -1
+}
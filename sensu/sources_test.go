@@ -0,0 +1,86 @@
+package sensu
+
+import (
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+// Test custom sources are applied at their precedence
+func TestGoHandler_Execute_ResolveFrom(t *testing.T) {
+	tests := []struct {
+		precedence     SourcePrecedence
+		env            string
+		args           []string
+		eventFile      string
+		expectedValue  string
+		expectedSource string
+	}{
+		{PrecedenceAboveDefault, "", []string{}, "test/event-no-override.json", "value-custom", "custom"},
+		{PrecedenceAboveDefault, "value-env", []string{}, "test/event-no-override.json", "value-env", sourceEnv},
+		{PrecedenceAboveEnv, "value-env", []string{}, "test/event-no-override.json", "value-custom", "custom"},
+		{PrecedenceAboveEnv, "", []string{"--arg1", "value-cmdline"}, "test/event-no-override.json", "value-cmdline", sourceCmdLine},
+		{PrecedenceAboveCmdLine, "", []string{"--arg1", "value-cmdline"}, "test/event-no-override.json", "value-custom", "custom"},
+		{PrecedenceAboveCmdLine, "", []string{"--arg1", "value-cmdline"}, "test/event-entity-override.json", "value-entity1", sourceEntity},
+		{PrecedenceAboveEntity, "", []string{}, "test/event-entity-override.json", "value-custom", "custom"},
+		{PrecedenceAboveEntity, "", []string{}, "test/event-check-entity-override.json", "value-check1", sourceCheck},
+		{PrecedenceAboveCheck, "", []string{"--arg1", "value-cmdline"}, "test/event-check-entity-override.json", "value-custom", "custom"},
+	}
+
+	for _, test := range tests {
+		clearEnvironment()
+		if len(test.env) > 0 {
+			_ = os.Setenv("ENV_1", test.env)
+		}
+		options := getDefaultOptions()
+		values := handlerValues{}
+		options[0].Value = &values.arg1
+		options[1].Value = &values.arg2
+		options[2].Value = &values.arg3
+
+		goHandler := NewGoHandler(&defaultHandlerConfig, options,
+			func(event *types.Event) error {
+				return nil
+			}, func(event *types.Event) error {
+				return nil
+			})
+		goHandler.ResolveFrom(map[string]string{"path1": "value-custom", "unknown": "ignored"}, "custom", test.precedence)
+		goHandler.cmdArgs.SetArgs(test.args)
+		goHandler.eventReader = getFileReader(test.eventFile)
+		err := goHandler.Execute()
+		_ = os.Unsetenv("ENV_1")
+
+		assert.Nil(t, err)
+		assert.Equal(t, test.expectedValue, values.arg1)
+		assert.Equal(t, test.expectedSource, options[0].source)
+	}
+}
+
+// Test the last custom source added wins and higher precedences override lower ones
+func TestGoHandler_Execute_ResolveFromOrder(t *testing.T) {
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.ResolveFrom(map[string]string{"path1": "value-high", "path2": "1"}, "high", PrecedenceAboveCmdLine)
+	goHandler.ResolveFrom(map[string]string{"path1": "value-low", "path2": "2"}, "low", PrecedenceAboveDefault)
+	goHandler.ResolveFrom(map[string]string{"path2": "3"}, "high2", PrecedenceAboveCmdLine)
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "value-high", values.arg1)
+	assert.Equal(t, uint64(3), values.arg2)
+	assert.Equal(t, "high2", options[1].source)
+}
@@ -0,0 +1,81 @@
+package sensu
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Formats of the summary printed by the --output flag
+const (
+	OutputFormatJSON = "json"
+)
+
+// Status of the handler execution reported by the summary
+const (
+	SummaryStatusOK              = "ok"
+	SummaryStatusValidationError = "validation_error"
+	SummaryStatusExecutionError  = "execution_error"
+	SummaryStatusError           = "error"
+)
+
+// executionSummary is the summary of the handler execution printed by
+// --output json. Event is the EventKey of the event handled, the events of a
+// batch are not included.
+type executionSummary struct {
+	Status     string                 `json:"status"`
+	Event      string                 `json:"event,omitempty"`
+	DurationMs int64                  `json:"duration_ms"`
+	Options    map[string]interface{} `json:"options"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// checkOutputFormat returns an error if the --output format is not supported
+func checkOutputFormat(outputFormat string) error {
+	if outputFormat == "" || outputFormat == OutputFormatJSON {
+		return nil
+	}
+	return fmt.Errorf("invalid output format %q, must be %s", outputFormat, OutputFormatJSON)
+}
+
+// summaryStatus returns the summary status of the error returned by the handler
+func summaryStatus(err error) string {
+	var validationErr *ValidationError
+	var executionErr *ExecutionError
+	switch {
+	case err == nil:
+		return SummaryStatusOK
+	case errors.As(err, &validationErr):
+		return SummaryStatusValidationError
+	case errors.As(err, &executionErr):
+		return SummaryStatusExecutionError
+	default:
+		return SummaryStatusError
+	}
+}
+
+// printSummary writes the summary of the handler execution to stdout when
+// --output is set
+func (goHandler *GoHandler) printSummary(err error, duration time.Duration) error {
+	if goHandler.outputFormat != OutputFormatJSON {
+		return nil
+	}
+
+	summary := executionSummary{
+		Status:     summaryStatus(err),
+		Event:      goHandler.eventKey,
+		DurationMs: int64(duration / time.Millisecond),
+		Options:    goHandler.ResolvedValues(),
+	}
+	if err != nil {
+		summary.Error = err.Error()
+	}
+
+	summaryJSON, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("error marshalling the summary: %s", err)
+	}
+	_, err = fmt.Fprintln(goHandler.outputWriter, string(summaryJSON))
+	return err
+}
@@ -0,0 +1,104 @@
+package sensu
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func executeWithSummary(t *testing.T, validationFunction func(*types.Event) error,
+	executeFunction func(*types.Event) error) (map[string]interface{}, error) {
+	var output bytes.Buffer
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options, validationFunction, executeFunction,
+		WithOutputWriter(&output))
+	goHandler.cmdArgs.SetArgs([]string{"--output", "json", "--arg3"})
+	goHandler.eventReader = getFileReader("test/event-check-override.json")
+	err := goHandler.Execute()
+
+	var summary map[string]interface{}
+	assert.Nil(t, json.Unmarshal(output.Bytes(), &summary), output.String())
+	return summary, err
+}
+
+func TestGoHandler_Execute_OutputJSON(t *testing.T) {
+	summary, err := executeWithSummary(t,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "ok", summary["status"])
+	assert.Equal(t, "webserver01/check-nginx", summary["event"])
+	assert.IsType(t, float64(0), summary["duration_ms"])
+	assert.Equal(t, map[string]interface{}{
+		"arg1": "value-check1",
+		"arg2": float64(1357),
+		"arg3": false,
+	}, summary["options"])
+	assert.NotContains(t, summary, "error")
+}
+
+func TestGoHandler_Execute_OutputJSONErrors(t *testing.T) {
+	summary, err := executeWithSummary(t,
+		func(event *types.Event) error {
+			return fmt.Errorf("invalid input")
+		}, func(event *types.Event) error {
+			return nil
+		})
+
+	assert.EqualError(t, err, "error validating input: invalid input")
+	assert.Equal(t, "validation_error", summary["status"])
+	assert.Equal(t, "error validating input: invalid input", summary["error"])
+
+	summary, err = executeWithSummary(t,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return fmt.Errorf("execution failed")
+		})
+
+	assert.EqualError(t, err, "error executing handler: execution failed")
+	assert.Equal(t, "execution_error", summary["status"])
+	assert.Equal(t, "error executing handler: execution failed", summary["error"])
+}
+
+func TestGoHandler_Execute_OutputDefault(t *testing.T) {
+	var output bytes.Buffer
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		}, WithOutputWriter(&output))
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Empty(t, output.String())
+}
+
+func TestCheckOutputFormat(t *testing.T) {
+	assert.Nil(t, checkOutputFormat(""))
+	assert.Nil(t, checkOutputFormat(OutputFormatJSON))
+	assert.EqualError(t, checkOutputFormat("xml"), `invalid output format "xml", must be json`)
+}
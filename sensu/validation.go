@@ -0,0 +1,148 @@
+package sensu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sensu/sensu-go/types"
+	"log"
+	"strings"
+	"text/template"
+)
+
+// validateOptions checks the options against their AllowedValues and runs
+// their Validate function
+func validateOptions(options []*HandlerConfigOption) error {
+	for _, opt := range options {
+		if err := checkAllowedValues(opt); err != nil {
+			return err
+		}
+		if err := checkTemplate(opt); err != nil {
+			return err
+		}
+		if err := checkPattern(opt); err != nil {
+			return err
+		}
+		if opt.Validate == nil {
+			continue
+		}
+		if err := opt.Validate(optionValue(opt)); err != nil {
+			return fmt.Errorf("invalid value for option %s: %s", opt.Argument, err)
+		}
+	}
+	return nil
+}
+
+// checkPattern returns an error if the resolved value of the option, or one of
+// its elements for []string options, does not match its Pattern
+func checkPattern(option *HandlerConfigOption) error {
+	if option.pattern == nil {
+		return nil
+	}
+
+	var values []string
+	switch value := optionValue(option).(type) {
+	case []string:
+		values = value
+	default:
+		values = []string{fmt.Sprint(value)}
+	}
+
+	for _, value := range values {
+		if !option.pattern.MatchString(value) {
+			return fmt.Errorf("value for option %s does not match pattern %s", option.Argument, option.Pattern)
+		}
+	}
+	return nil
+}
+
+// checkTemplate returns an error if the option is a TemplateString whose value
+// is not a valid Go template. The template is parsed but not executed.
+func checkTemplate(option *HandlerConfigOption) error {
+	if !option.TemplateString {
+		return nil
+	}
+
+	if _, err := template.New(option.Argument).Parse(fmt.Sprint(optionValue(option))); err != nil {
+		return fmt.Errorf("invalid template for option %s: %s", option.Argument, err)
+	}
+	return nil
+}
+
+// checkAllowedValues returns an error if the option's value, or any of its
+// elements for string slices, is not one of its AllowedValues
+func checkAllowedValues(option *HandlerConfigOption) error {
+	if len(option.AllowedValues) == 0 {
+		return nil
+	}
+
+	var values []string
+	switch value := optionValue(option).(type) {
+	case []string:
+		values = value
+	default:
+		values = []string{fmt.Sprint(value)}
+	}
+
+	for _, value := range values {
+		allowed := false
+		for _, allowedValue := range option.AllowedValues {
+			if value == allowedValue || (option.CaseInsensitive && strings.EqualFold(value, allowedValue)) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("invalid value %q for %s, must be one of %v", displayValue(option, value), option.Argument,
+				option.AllowedValues)
+		}
+	}
+	return nil
+}
+
+// validationError wraps the validation function error, ignoring it when the
+// configuration is in warn mode
+func validationError(config *HandlerConfig, err error, errorLog *log.Logger) error {
+	if err != nil {
+		if config.ValidationFailureMode != ValidationFailureWarn {
+			return &ValidationError{Err: err}
+		}
+		errorLog.Printf("Ignoring validation error: %s\n", err)
+	}
+	return nil
+}
+
+// Validate checks the resolved option values and runs the validation function
+// on the event.
+func (goHandler *GoHandler) Validate(sensuEvent *types.Event) error {
+	ctx, cancel := timeoutContext(goHandler.config.Timeout)
+	defer cancel()
+	goHandler.sensuEvent = sensuEvent
+
+	return goHandler.validate(ctx)
+}
+
+// validate checks the resolved option values and runs the validation function
+func (goHandler *GoHandler) validate(ctx context.Context) error {
+	err := goHandler.validateInput(ctx, goHandler.sensuEvent, goHandler.validationFunction)
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		goHandler.log(LogLevelError, "input validation failed", map[string]interface{}{"error": validationErr.Err.Error()})
+	} else if err == nil {
+		goHandler.log(LogLevelDebug, "input validated", nil)
+	}
+	return err
+}
+
+// recoverHandler calls a validation or execute function, returning an error
+// instead of crashing if it panics
+func recoverHandler(ctx context.Context, event *types.Event,
+	function func(ctx context.Context, event *types.Event) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+
+	return function(ctx, event)
+}
@@ -0,0 +1,372 @@
+package sensu
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestCheckAllowedValues(t *testing.T) {
+	tests := []struct {
+		value           string
+		allowedValues   []string
+		caseInsensitive bool
+		expectedErr     string
+	}{
+		{"b", []string{"a", "b", "c"}, false, ""},
+		{"x", []string{"a", "b", "c"}, false, `invalid value "x" for arg1, must be one of [a b c]`},
+		{"B", []string{"a", "b", "c"}, false, `invalid value "B" for arg1, must be one of [a b c]`},
+		{"B", []string{"a", "b", "c"}, true, ""},
+		{"", []string{"a", "b", "c"}, false, `invalid value "" for arg1, must be one of [a b c]`},
+		{"anything", []string{}, false, ""},
+		{"anything", nil, false, ""},
+	}
+
+	for _, test := range tests {
+		value := test.value
+		option := defaultOption1
+		option.Value = &value
+		option.AllowedValues = test.allowedValues
+		option.CaseInsensitive = test.caseInsensitive
+		err := checkAllowedValues(&option)
+		if len(test.expectedErr) > 0 {
+			assert.EqualError(t, err, test.expectedErr)
+		} else {
+			assert.Nil(t, err)
+		}
+	}
+}
+
+func TestCheckAllowedValues_NonString(t *testing.T) {
+	sliceValue := []string{"a", "z"}
+	option := defaultOption1
+	option.Value = &sliceValue
+	option.AllowedValues = []string{"a", "b"}
+	assert.EqualError(t, checkAllowedValues(&option), `invalid value "z" for arg1, must be one of [a b]`)
+
+	uint64Value := uint64(2)
+	option.Value = &uint64Value
+	option.AllowedValues = []string{"1", "2"}
+	assert.Nil(t, checkAllowedValues(&option))
+}
+
+func TestCheckTemplate(t *testing.T) {
+	value := "{{ .Entity.Name }}/{{ .Check.Name }}"
+	option := defaultOption1
+	option.Value = &value
+	option.TemplateString = true
+	assert.Nil(t, checkTemplate(&option))
+
+	value = "{{ .Entity.Name "
+	assert.EqualError(t, checkTemplate(&option),
+		"invalid template for option arg1: template: arg1:1: unclosed action")
+
+	option.TemplateString = false
+	assert.Nil(t, checkTemplate(&option))
+}
+
+func TestGoHandler_Execute_TemplateString(t *testing.T) {
+	var validateCalled bool
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[0].TemplateString = true
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			validateCalled = true
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{"--arg1", "{{ if .Check }}"})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid template for option arg1: ")
+	assert.False(t, validateCalled)
+}
+
+// Test validation error
+func TestGoHandler_Execute_ValidationError(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	err := goHandlerExecuteUtil(t, &defaultHandlerConfig, "test/event-no-override.json", defaultCmdLineArgs,
+		func(event *types.Event) error {
+			validateCalled = true
+			assert.NotNil(t, event)
+			return fmt.Errorf("validation error")
+		}, func(event *types.Event) error {
+			executeCalled = true
+			return nil
+		},
+		"value-arg1", uint64(7531), false)
+	assert.NotNil(t, err)
+	assert.EqualError(t, err, "error validating input: validation error")
+	assert.True(t, validateCalled)
+	assert.False(t, executeCalled)
+}
+
+// Test validation error in warn mode
+func TestGoHandler_Execute_ValidationErrorWarnMode(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	handlerConfig := defaultHandlerConfig
+	handlerConfig.ValidationFailureMode = ValidationFailureWarn
+	err := goHandlerExecuteUtil(t, &handlerConfig, "test/event-no-override.json", defaultCmdLineArgs,
+		func(event *types.Event) error {
+			validateCalled = true
+			return fmt.Errorf("validation error")
+		}, func(event *types.Event) error {
+			executeCalled = true
+			return nil
+		},
+		"value-arg1", uint64(7531), false)
+	assert.Nil(t, err)
+	assert.True(t, validateCalled)
+	assert.True(t, executeCalled)
+}
+
+// Test validation error in explicit error mode
+func TestGoHandler_Execute_ValidationErrorErrorMode(t *testing.T) {
+	var validateCalled, executeCalled bool
+	clearEnvironment()
+	handlerConfig := defaultHandlerConfig
+	handlerConfig.ValidationFailureMode = ValidationFailureError
+	err := goHandlerExecuteUtil(t, &handlerConfig, "test/event-no-override.json", defaultCmdLineArgs,
+		func(event *types.Event) error {
+			validateCalled = true
+			return fmt.Errorf("validation error")
+		}, func(event *types.Event) error {
+			executeCalled = true
+			return nil
+		},
+		"value-arg1", uint64(7531), false)
+	assert.EqualError(t, err, "error validating input: validation error")
+	assert.True(t, validateCalled)
+	assert.False(t, executeCalled)
+}
+
+func portValidator(value interface{}) error {
+	port := value.(uint64)
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("port %d must be between 1 and 65535", port)
+	}
+	return nil
+}
+
+// Test passing option validator
+func TestGoHandler_Execute_OptionValidator(t *testing.T) {
+	var validateCalled, executeCalled bool
+	var port uint64
+	clearEnvironment()
+	portOption := HandlerConfigOption{
+		Argument: "port",
+		Default:  uint64(8080),
+		Path:     "path2",
+		Usage:    "Port",
+		Value:    &port,
+		Validate: portValidator,
+	}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, []*HandlerConfigOption{&portOption},
+		func(event *types.Event) error {
+			validateCalled = true
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-check-override.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1357), port)
+	assert.True(t, validateCalled)
+	assert.True(t, executeCalled)
+}
+
+// Test failing option validator
+func TestGoHandler_Execute_OptionValidatorError(t *testing.T) {
+	var validateCalled, executeCalled bool
+	var port uint64
+	clearEnvironment()
+	portOption := HandlerConfigOption{
+		Argument: "port",
+		Default:  uint64(8080),
+		Usage:    "Port",
+		Value:    &port,
+		Validate: portValidator,
+	}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, []*HandlerConfigOption{&portOption},
+		func(event *types.Event) error {
+			validateCalled = true
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{"--port", "70000"})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.EqualError(t, err, "invalid value for option port: port 70000 must be between 1 and 65535")
+	assert.False(t, validateCalled)
+	assert.False(t, executeCalled)
+}
+
+// Test allowed values are enforced on annotation overrides
+func TestGoHandler_Execute_AllowedValues(t *testing.T) {
+	var validateCalled bool
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[0].AllowedValues = []string{"Default1", "value-arg1"}
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			validateCalled = true
+			return nil
+		}, func(event *types.Event) error {
+			return nil
+		})
+	goHandler.cmdArgs.SetArgs([]string{})
+	goHandler.eventReader = getFileReader("test/event-check-override.json")
+	err := goHandler.Execute()
+
+	assert.EqualError(t, err, `invalid value "value-check1" for arg1, must be one of [Default1 value-arg1]`)
+	assert.False(t, validateCalled)
+}
+
+// Test the resolved value must match the option pattern
+func TestGoHandler_Execute_Pattern(t *testing.T) {
+	tests := []struct {
+		pattern     string
+		args        []string
+		eventFile   string
+		expectedErr string
+	}{
+		{"", []string{"--arg1", "anything goes"}, "test/event-no-override.json", ""},
+		{"^[a-z]+-[a-z0-9]+$", []string{"--arg1", "value-arg1"}, "test/event-no-override.json", ""},
+		{"^[a-z]+-[a-z0-9]+$", []string{"--arg1", "Value_arg1"}, "test/event-no-override.json",
+			"value for option arg1 does not match pattern ^[a-z]+-[a-z0-9]+$"},
+		{"^value-arg", []string{"--arg1", "value-arg1"}, "test/event-check-override.json",
+			"value for option arg1 does not match pattern ^value-arg"},
+		{"^[a-z", []string{}, "test/event-no-override.json",
+			"invalid pattern \"^[a-z\" for option arg1: error parsing regexp: missing closing ]: `[a-z`"},
+	}
+
+	for _, test := range tests {
+		var validateCalled bool
+		clearEnvironment()
+		options := getDefaultOptions()
+		values := handlerValues{}
+		options[0].Value = &values.arg1
+		options[0].Pattern = test.pattern
+		options[1].Value = &values.arg2
+		options[2].Value = &values.arg3
+
+		goHandler := NewGoHandler(&defaultHandlerConfig, options,
+			func(event *types.Event) error {
+				validateCalled = true
+				return nil
+			}, func(event *types.Event) error {
+				return nil
+			}, WithErrorWriter(ioutil.Discard))
+		goHandler.cmdArgs.SetArgs(test.args)
+		goHandler.eventReader = getFileReader(test.eventFile)
+		err := goHandler.Execute()
+
+		if len(test.expectedErr) > 0 {
+			assert.EqualError(t, err, test.expectedErr, test.pattern)
+			assert.False(t, validateCalled, test.pattern)
+		} else {
+			assert.Nil(t, err, test.pattern)
+			assert.True(t, validateCalled, test.pattern)
+		}
+	}
+}
+
+// Test the allowed values apply to every source
+func TestGoHandler_Execute_AllowedValuesSources(t *testing.T) {
+	tests := []struct {
+		env         string
+		args        []string
+		expectedErr string
+	}{
+		{"", []string{}, ""},
+		{"", []string{"--arg1", "medium"}, ""},
+		{"", []string{"--arg1", "urgent"}, `invalid value "urgent" for arg1, must be one of [Default1 low medium high]`},
+		{"high", []string{}, ""},
+		{"HIGH", []string{}, `invalid value "HIGH" for arg1, must be one of [Default1 low medium high]`},
+	}
+
+	for _, test := range tests {
+		clearEnvironment()
+		if len(test.env) > 0 {
+			_ = os.Setenv("ENV_1", test.env)
+		}
+		options := getDefaultOptions()
+		values := handlerValues{}
+		options[0].Value = &values.arg1
+		options[0].AllowedValues = []string{"Default1", "low", "medium", "high"}
+		options[1].Value = &values.arg2
+		options[2].Value = &values.arg3
+
+		goHandler := NewGoHandler(&defaultHandlerConfig, options,
+			func(event *types.Event) error {
+				return nil
+			}, func(event *types.Event) error {
+				return nil
+			})
+		goHandler.cmdArgs.SetArgs(test.args)
+		goHandler.eventReader = getFileReader("test/event-no-override.json")
+		err := goHandler.Execute()
+		_ = os.Unsetenv("ENV_1")
+
+		if len(test.expectedErr) > 0 {
+			assert.EqualError(t, err, test.expectedErr)
+		} else {
+			assert.Nil(t, err)
+		}
+	}
+}
+
+func TestGoHandler_Validate_Error(t *testing.T) {
+	clearEnvironment()
+	options := getDefaultOptions()
+	values := handlerValues{}
+	options[0].Value = &values.arg1
+	options[1].Value = &values.arg2
+	options[2].Value = &values.arg3
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return fmt.Errorf("validation error")
+		}, func(event *types.Event) error {
+			return nil
+		}, WithEventReader(getFileReader("test/event-no-override.json")))
+
+	event, err := goHandler.ReadEvent()
+	assert.Nil(t, err)
+	err = goHandler.ResolveOptions(event)
+	assert.Nil(t, err)
+	assert.Equal(t, "Default1", values.arg1)
+
+	err = goHandler.Validate(event)
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+}
@@ -0,0 +1,52 @@
+package sensu
+
+import (
+	"fmt"
+	"github.com/sensu/sensu-enterprise-go-plugin/args"
+	"io"
+	"runtime"
+)
+
+// PluginVersion is the version reported by --version when the HandlerConfig
+// does not set one. It is meant to be set at build time:
+//
+//	go build -ldflags "-X github.com/sensu/sensu-enterprise-go-plugin/sensu.PluginVersion=1.2.3"
+var PluginVersion = ""
+
+// registerVersionFlag adds the --version flag, with the -v shorthand unless an
+// option already uses it
+func registerVersionFlag(cmdArgs *args.Args, options []*HandlerConfigOption, showVersion *bool) {
+	shorthand := "v"
+	for _, opt := range options {
+		if opt.Shorthand == shorthand {
+			shorthand = ""
+		}
+	}
+	cmdArgs.BoolVarP(showVersion, "version", shorthand, "", false,
+		"Print the version of the plugin and exit")
+}
+
+// writeVersion writes the plugin version, the Go version it was built with
+// and, when available, the version control information of the build
+func writeVersion(writer io.Writer, config *HandlerConfig) error {
+	version := config.Version
+	if len(version) == 0 {
+		version = PluginVersion
+	}
+	if len(version) == 0 {
+		version = "unknown"
+	}
+
+	if _, err := fmt.Fprintf(writer, "%s version %s\n", config.Name, version); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(writer, "Built with %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH); err != nil {
+		return err
+	}
+	if revision := vcsRevision(); len(revision) > 0 {
+		if _, err := fmt.Fprintf(writer, "Revision %s\n", revision); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+//go:build go1.18
+// +build go1.18
+
+package sensu
+
+import (
+	"runtime/debug"
+)
+
+// vcsRevision returns the version control revision the plugin was built from,
+// followed by its commit time and whether the tree was modified, or an empty
+// string when the build carries no version control information
+func vcsRevision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	var revision, time string
+	modified := false
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.time":
+			time = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if len(revision) == 0 {
+		return ""
+	}
+	if len(time) > 0 {
+		revision += " (" + time + ")"
+	}
+	if modified {
+		revision += " with local modifications"
+	}
+	return revision
+}
@@ -0,0 +1,10 @@
+//go:build !go1.18
+// +build !go1.18
+
+package sensu
+
+// vcsRevision returns an empty string, the version control information of the
+// build is only available from Go 1.18
+func vcsRevision() string {
+	return ""
+}
@@ -0,0 +1,99 @@
+package sensu
+
+import (
+	"bytes"
+	"github.com/sensu/sensu-go/types"
+	"github.com/stretchr/testify/assert"
+	"runtime"
+	"testing"
+)
+
+func TestGoHandler_Execute_Version(t *testing.T) {
+	for _, flag := range []string{"--version", "-v"} {
+		var output bytes.Buffer
+		var executeCalled bool
+		handlerConfig := defaultHandlerConfig
+		handlerConfig.Version = "1.2.3"
+		options := getDefaultOptions()
+		values := handlerValues{}
+		options[0].Value = &values.arg1
+		options[1].Value = &values.arg2
+		options[2].Value = &values.arg3
+
+		goHandler := NewGoHandler(&handlerConfig, options,
+			func(event *types.Event) error {
+				return nil
+			}, func(event *types.Event) error {
+				executeCalled = true
+				return nil
+			}, WithOutputWriter(&output))
+		goHandler.cmdArgs.SetArgs([]string{flag})
+		// The event is not read
+		goHandler.eventReader = getFileReader("test/event-invalid-json.json")
+		err := goHandler.Execute()
+
+		assert.Nil(t, err, flag)
+		assert.False(t, executeCalled, flag)
+		assert.Contains(t, output.String(), "TestHandler version 1.2.3\n", flag)
+		assert.Contains(t, output.String(), "Built with "+runtime.Version(), flag)
+	}
+}
+
+func TestGoMutator_Execute_Version(t *testing.T) {
+	defer func(version string) {
+		PluginVersion = version
+	}(PluginVersion)
+	PluginVersion = "2.0.0"
+	var output bytes.Buffer
+	goMutator := NewGoMutator(&defaultHandlerConfig, []*HandlerConfigOption{}, func(event *types.Event) error {
+		return nil
+	}, func(event *types.Event) ([]byte, error) {
+		return []byte("mutated"), nil
+	})
+	goMutator.SetOutputWriter(&output)
+	goMutator.cmdArgs.SetArgs([]string{"--version"})
+	goMutator.eventReader = getFileReader("test/event-invalid-json.json")
+	err := goMutator.Execute()
+
+	assert.Nil(t, err)
+	assert.Contains(t, output.String(), "TestHandler version 2.0.0\n")
+	assert.NotContains(t, output.String(), "mutated")
+}
+
+func TestRegisterVersionFlag_ShorthandTaken(t *testing.T) {
+	var output bytes.Buffer
+	var verbose, executeCalled bool
+	clearEnvironment()
+	options := []*HandlerConfigOption{
+		{Argument: "verbose", Shorthand: "v", Value: &verbose, Default: false},
+	}
+
+	goHandler := NewGoHandler(&defaultHandlerConfig, options,
+		func(event *types.Event) error {
+			return nil
+		}, func(event *types.Event) error {
+			executeCalled = true
+			return nil
+		}, WithOutputWriter(&output))
+	goHandler.cmdArgs.SetArgs([]string{"-v"})
+	goHandler.eventReader = getFileReader("test/event-no-override.json")
+	err := goHandler.Execute()
+
+	assert.Nil(t, err)
+	assert.True(t, verbose)
+	assert.True(t, executeCalled)
+	assert.Empty(t, output.String())
+}
+
+func TestWriteVersion(t *testing.T) {
+	defer func(version string) {
+		PluginVersion = version
+	}(PluginVersion)
+	var output bytes.Buffer
+	PluginVersion = ""
+
+	err := writeVersion(&output, &HandlerConfig{Name: "my-plugin"})
+
+	assert.Nil(t, err)
+	assert.Contains(t, output.String(), "my-plugin version unknown\n")
+}